@@ -0,0 +1,113 @@
+package event
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+)
+
+const (
+	// ContentEncodingAttr is the message attribute that indicates how the message body is encoded.
+	// It is set to [ContentEncodingGzip] by publishers created with [PublisherWithBodyCompression]
+	// when the event is compressed. Messages without the attribute are plain JSON.
+	ContentEncodingAttr = "content-encoding"
+	// ContentEncodingGzip indicates that the message body is the event envelope compressed with gzip.
+	ContentEncodingGzip = "gzip"
+
+	// DefaultMaxDecompressedSize is the default max size of decompressed event bodies,
+	// see [SubscriptionWithMaxDecompressedSize].
+	DefaultMaxDecompressedSize = 100 * 1024 * 1024
+
+	// gzipTrailerSize is the size of the uncompressed data size (ISIZE) at the end of gzip streams, see RFC 1952.
+	gzipTrailerSize = 4
+	// gzipMaxRatio is the max compression ratio of deflate, bigger sizes on the gzip trailer are invalid.
+	gzipMaxRatio = 1032
+)
+
+// PublisherWithBodyCompression configures the publisher to compress with gzip the encoded events that are
+// bigger than threshold bytes, helping large events to fit on the broker message size limits (like GCP's 10MB).
+// Compressed messages have the [ContentEncodingAttr] attribute set to [ContentEncodingGzip] and are decompressed
+// transparently by [Subscription]. Events smaller or equal than threshold are published as is, so they are still
+// readable by consumers that don't support compression.
+func PublisherWithBodyCompression(threshold int) PublisherOption {
+	return func(o *publisherOptions) {
+		o.compressionThreshold = threshold
+	}
+}
+
+// compressBody compresses the given body if the publisher is configured to do so and the body is bigger than the
+// configured threshold. It returns the body and attributes that should be published, the given attributes are never
+// modified, a copy is made if the content encoding needs to be added.
+func (o publisherOptions) compressBody(body []byte, attributes map[string]string) ([]byte, map[string]string, error) {
+	if o.compressionThreshold <= 0 || len(body) <= o.compressionThreshold {
+		return body, attributes, nil
+	}
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(body); err != nil {
+		return nil, nil, fmt.Errorf("compressing event body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, fmt.Errorf("compressing event body: %w", err)
+	}
+
+	newAttributes := make(map[string]string, len(attributes)+1)
+	maps.Copy(newAttributes, attributes)
+	newAttributes[ContentEncodingAttr] = ContentEncodingGzip
+	return compressed.Bytes(), newAttributes, nil
+}
+
+// SubscriptionWithMaxDecompressedSize configures the max size in bytes of decompressed event bodies (see
+// [PublisherWithBodyCompression]), protecting subscribers from small messages that decompress to huge bodies.
+// Events bigger than maxSize when decompressed are discarded as malformed. Defaults to [DefaultMaxDecompressedSize].
+func SubscriptionWithMaxDecompressedSize(maxSize int) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.maxDecompressedSize = maxSize
+	}
+}
+
+// uncompressedSize returns the size of the message body after decompression, without decompressing it.
+// The size of gzip bodies is the size stored on the gzip trailer (modulo 2^32, bodies are never that big).
+// The trailer is not trusted, sizes that are impossible to achieve with gzip are ignored (the body size is returned).
+func uncompressedSize(msg Message) int {
+	if msg.Metadata.Attributes[ContentEncodingAttr] != ContentEncodingGzip || len(msg.Body) < gzipTrailerSize {
+		return len(msg.Body)
+	}
+	size := int(binary.LittleEndian.Uint32(msg.Body[len(msg.Body)-gzipTrailerSize:]))
+	if size > len(msg.Body)*gzipMaxRatio {
+		return len(msg.Body)
+	}
+	return size
+}
+
+// decompressBody returns the decompressed body of the given message, according to its content encoding.
+// Messages without a content encoding are returned as is.
+// It fails if the decompressed body is bigger than maxSize, without decompressing more than that.
+func decompressBody(msg Message, maxSize int) ([]byte, error) {
+	switch encoding := msg.Metadata.Attributes[ContentEncodingAttr]; encoding {
+	case "":
+		return msg.Body, nil
+	case ContentEncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(msg.Body))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing event body: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing event body: %w", err)
+		}
+		if len(body) > maxSize {
+			return nil, fmt.Errorf("decompressing event body: body exceeds max size of %d bytes", maxSize)
+		}
+		if err := r.Close(); err != nil {
+			return nil, fmt.Errorf("decompressing event body: %w", err)
+		}
+		return body, nil
+	default:
+		return nil, fmt.Errorf("unsupported event content encoding %q", encoding)
+	}
+}
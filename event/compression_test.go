@@ -0,0 +1,200 @@
+package event_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestPublisherWithBodyCompression(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const (
+		eventName = "test"
+		threshold = 100
+	)
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := pubsub.OpenSubscription(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[string](eventName, topic, event.PublisherWithBodyCompression(threshold))
+	bigEvent := strings.Repeat("a", threshold)
+	attrs := map[string]string{"key": "value"}
+
+	if err := publisher.PublishWithAttrs(ctx, bigEvent, attrs); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, attrs, map[string]string{"key": "value"})
+
+	msg, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Ack()
+
+	assertEqual(t, msg.Metadata, map[string]string{
+		"key":                     "value",
+		event.ContentEncodingAttr: event.ContentEncodingGzip,
+	})
+	r, err := gzip.NewReader(bytes.NewReader(msg.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := event.DecodeEnvelope(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(envelope.Event), `"`+bigEvent+`"`)
+
+	if err := publisher.Publish(ctx, "small"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err = subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Ack()
+
+	if _, ok := msg.Metadata[event.ContentEncodingAttr]; ok {
+		t.Fatalf("small event should not be compressed, got metadata: %v", msg.Metadata)
+	}
+	envelope, err = event.DecodeEnvelope(msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(envelope.Event), `"small"`)
+}
+
+func TestSubscriptionDecompressesEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[string](eventName, topic, event.PublisherWithBodyCompression(10))
+	want := map[string]bool{
+		strings.Repeat("compressed", 100): true,
+		"small":                           true,
+	}
+	for e := range want {
+		if err := publisher.Publish(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Delivery order is not guaranteed
+	got := map[string]bool{}
+	for range len(want) {
+		e, err := subscription.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Ack()
+		got[e.Event] = true
+	}
+	assertEqual(t, got, want)
+}
+
+func TestSubscriptionDiscardsUnsupportedEncoding(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	err = topic.Send(ctx, &pubsub.Message{
+		Body:     []byte(`{"name":"test","event":"data"}`),
+		Metadata: map[string]string{event.ContentEncodingAttr: "br"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := subscription.Receive(ctx); err == nil {
+		t.Fatal("want error receiving event with unsupported encoding, got nil")
+	}
+	assertEqual(t, subscription.Stats().Malformed, 1)
+}
+
+func TestSubscriptionDiscardsEventsBiggerThanMaxDecompressedSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string](eventName, url, 1, event.SubscriptionWithMaxDecompressedSize(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	// Compresses to a few bytes, but it is much bigger than the max size when decompressed
+	publisher := event.NewPublisher[string](eventName, topic, event.PublisherWithBodyCompression(10))
+	if err := publisher.Publish(ctx, strings.Repeat("a", 10000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := subscription.Receive(ctx); err == nil {
+		t.Fatal("want error receiving event bigger than max decompressed size, got nil")
+	}
+	assertEqual(t, subscription.Stats().Malformed, 1)
+
+	if err := publisher.Publish(ctx, strings.Repeat("a", 500)); err != nil {
+		t.Fatal(err)
+	}
+	e, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Ack()
+	assertEqual(t, e.Event, strings.Repeat("a", 500))
+}
@@ -0,0 +1,69 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// handlerGroup tracks the message handlers started by [MessageSubscription.Serve],
+// so shutting down can stop receiving messages and wait for the running handlers.
+type handlerGroup struct {
+	// receiveCtx is used to receive messages, it is canceled when the group is stopped.
+	receiveCtx    context.Context
+	stopReceiving context.CancelFunc
+
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+	running atomic.Int64
+}
+
+func newHandlerGroup() *handlerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &handlerGroup{
+		receiveCtx:    ctx,
+		stopReceiving: cancel,
+	}
+}
+
+// start registers a new running handler, it returns false if the group is stopped (no handler should be started).
+func (g *handlerGroup) start() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return false
+	}
+	g.wg.Add(1)
+	g.running.Add(1)
+	return true
+}
+
+// done must be called when a handler registered with start finishes.
+func (g *handlerGroup) done() {
+	g.running.Add(-1)
+	g.wg.Done()
+}
+
+// stop stops receiving messages and waits for all running handlers to finish, until the given context is done.
+func (g *handlerGroup) stop(ctx context.Context) error {
+	g.mu.Lock()
+	g.stopped = true
+	g.stopReceiving()
+	g.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for handlers to finish: %d handlers still running: %w", g.running.Load(), ctx.Err())
+	}
+}
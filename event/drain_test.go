@@ -0,0 +1,126 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestShutdownWaitsRunningHandlers(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	// With max concurrency 1 serving would only stop after the handler finishes, since it waits for a free slot.
+	subscription, err := event.NewSubscription[int]("test", url, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(context.Context, int) error {
+			close(handling)
+			<-release
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	if err := event.NewPublisher[int]("test", topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	<-handling
+
+	shutdownErr := make(chan error)
+	go func() {
+		shutdownErr <- subscription.Shutdown(ctx)
+	}()
+
+	// Serving stops receiving right away, but shutdown waits for the running handler.
+	<-servingDone
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("shutdown returned before handler finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdownErr; err != nil {
+		t.Fatal(err)
+	}
+	stats := subscription.Stats()
+	assertEqual(t, stats.Acked, uint64(1))
+	assertEqual(t, stats.Nacked, uint64(0))
+}
+
+func TestShutdownTimeoutWithRunningHandlers(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[int]("test", url, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Published before serving, so they are received without waiting for the broker to poll.
+	publisher := event.NewPublisher[int]("test", topic)
+	for _, v := range []int{1, 2} {
+		if err := publisher.Publish(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(context.Context, int) error {
+			handling <- struct{}{}
+			<-release
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+	<-handling
+	<-handling
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err = subscription.Shutdown(shutdownCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+	if !strings.Contains(err.Error(), "2 handlers still running") {
+		t.Fatalf("got error %q; want amount of running handlers", err)
+	}
+
+	// Serving is blocked waiting for a free slot, it stops only after the handlers finish.
+	close(release)
+	<-servingDone
+}
@@ -0,0 +1,97 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// DryRunPublisher captures events in memory instead of publishing them, like for dry runs of tools that
+// publish events or for previewing what would be published. It has the same publish methods as [Publisher],
+// so code that depends on an interface with these methods can use both.
+// It is safe to use concurrently.
+type DryRunPublisher[T any] struct {
+	name      string
+	mutex     sync.Mutex
+	published []Envelope[T]
+}
+
+// dryRunIDPrefix is the prefix of the synthetic IDs returned by [DryRunPublisher.PublishAndGetID].
+const dryRunIDPrefix = "dry-run-"
+
+// NewDryRunPublisher creates a new [DryRunPublisher] for the given event name.
+// Like [NewPublisher] it panics if name is empty.
+func NewDryRunPublisher[T any](name string) *DryRunPublisher[T] {
+	if name == "" {
+		panic("event: creating dry run publisher: event name can't be empty")
+	}
+	return &DryRunPublisher[T]{name: name}
+}
+
+// Name returns the name of the event.
+func (p *DryRunPublisher[T]) Name() string {
+	return p.name
+}
+
+// Publish captures the given event, see [Publisher.Publish].
+func (p *DryRunPublisher[T]) Publish(ctx context.Context, event T) error {
+	return p.PublishWithAttrs(ctx, event, nil)
+}
+
+// PublishWithAttrs captures the given event, see [Publisher.PublishWithAttrs].
+// The event is encoded and decoded back exactly like it would be by a [Publisher] and a [Subscription],
+// so the captured [Envelope] is the same as the one that would be received and events that can't be
+// encoded fail like they would when publishing. The attributes are ignored.
+func (p *DryRunPublisher[T]) PublishWithAttrs(ctx context.Context, event T, _ map[string]string) error {
+	_, err := p.publish(ctx, event)
+	return err
+}
+
+// PublishAndGetID captures the given event, see [Publisher.PublishAndGetID].
+// The returned ID is synthetic, it is unique among the events captured by the publisher
+// and it is clearly not a broker ID, like "dry-run-1" for the first captured event.
+func (p *DryRunPublisher[T]) PublishAndGetID(ctx context.Context, event T) (string, error) {
+	return p.publish(ctx, event)
+}
+
+func (p *DryRunPublisher[T]) publish(ctx context.Context, event T) (string, error) {
+	encBody, err := json.Marshal(newEnvelope(ctx, p.name, event))
+	if err != nil {
+		return "", err
+	}
+	var envelope Envelope[T]
+	if err := json.Unmarshal(encBody, &envelope); err != nil {
+		return "", fmt.Errorf("decoding published event: %w", err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.published = append(p.published, envelope)
+	return dryRunIDPrefix + strconv.Itoa(len(p.published)), nil
+}
+
+// PublishBatch captures all the given events, see [Publisher.PublishBatch].
+func (p *DryRunPublisher[T]) PublishBatch(ctx context.Context, events []T) []error {
+	return p.PublishBatchWithAttrs(ctx, events, nil)
+}
+
+// PublishBatchWithAttrs captures all the given events, see [Publisher.PublishBatchWithAttrs].
+// Unlike a [Publisher] the events are captured in the given order. The attributes are ignored.
+func (p *DryRunPublisher[T]) PublishBatchWithAttrs(ctx context.Context, events []T, attributes map[string]string) []error {
+	errs := make([]error, len(events))
+	for i, event := range events {
+		errs[i] = p.PublishWithAttrs(ctx, event, attributes)
+	}
+	return errs
+}
+
+// Published returns all the events captured so far, in the order they were published.
+func (p *DryRunPublisher[T]) Published() []Envelope[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]Envelope[T](nil), p.published...)
+}
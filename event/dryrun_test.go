@@ -0,0 +1,121 @@
+package event_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/event"
+	"github.com/birdie-ai/golibs/tracing"
+	"gocloud.dev/pubsub"
+)
+
+type publisher[T any] interface {
+	Publish(context.Context, T) error
+	PublishWithAttrs(context.Context, T, map[string]string) error
+	PublishAndGetID(context.Context, T) (string, error)
+	PublishBatch(context.Context, []T) []error
+	PublishBatchWithAttrs(context.Context, []T, map[string]string) []error
+}
+
+var (
+	_ publisher[int] = &event.Publisher[int]{}
+	_ publisher[int] = &event.DryRunPublisher[int]{}
+)
+
+func TestDryRunPublisher(t *testing.T) {
+	t.Parallel()
+
+	type Event struct {
+		ID     int            `json:"id"`
+		Fields map[string]any `json:"fields"`
+	}
+	const eventName = "test"
+
+	ctx := context.Background()
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[Event](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	ctx = tracing.CtxWithTraceID(ctx, "trace-id")
+	ctx = tracing.CtxWithOrgID(ctx, "org-id")
+	e := Event{ID: 1, Fields: map[string]any{"count": 1, "name": "test"}}
+
+	dryRun := event.NewDryRunPublisher[Event](eventName)
+	if err := dryRun.Publish(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	errs := dryRun.PublishBatch(ctx, []Event{{ID: 2}, {ID: 3}})
+	assertEqual(t, errs, []error{nil, nil})
+	errs = dryRun.PublishBatchWithAttrs(ctx, []Event{{ID: 4}}, map[string]string{"key": "value"})
+	assertEqual(t, errs, []error{nil})
+
+	if err := event.NewPublisher[Event](eventName, topic).Publish(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	received, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received.Ack()
+
+	published := dryRun.Published()
+	assertEqual(t, len(published), 4)
+	// Captured events must be the same as the received ones (like numbers decoded as float64)
+	assertEqual(t, published[0], received.Envelope)
+	assertEqual(t, published[1].Event.ID, 2)
+	assertEqual(t, published[2].Event.ID, 3)
+	assertEqual(t, published[3].Event.ID, 4)
+	assertEqual(t, dryRun.Name(), eventName)
+}
+
+func TestDryRunPublisherEncodingError(t *testing.T) {
+	t.Parallel()
+
+	dryRun := event.NewDryRunPublisher[func()]("test")
+	if err := dryRun.Publish(context.Background(), func() {}); err == nil {
+		t.Fatal("want error publishing event that can't be encoded, got nil")
+	}
+	assertEqual(t, len(dryRun.Published()), 0)
+
+	if _, err := dryRun.PublishAndGetID(context.Background(), func() {}); err == nil {
+		t.Fatal("want error publishing event that can't be encoded, got nil")
+	}
+}
+
+func TestDryRunPublisherPublishAndGetID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dryRun := event.NewDryRunPublisher[int]("test")
+	if err := dryRun.Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]bool{}
+	for _, e := range []int{2, 3} {
+		id, err := dryRun.PublishAndGetID(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(id, "dry-run-") {
+			t.Fatalf("got ID %q; want synthetic dry run ID", id)
+		}
+		ids[id] = true
+	}
+	assertEqual(t, len(ids), 2)
+
+	published := dryRun.Published()
+	assertEqual(t, len(published), 3)
+	assertEqual(t, published[1].Event, 2)
+	assertEqual(t, published[2].Event, 3)
+}
@@ -0,0 +1,59 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/birdie-ai/golibs/tracing"
+)
+
+// Dump receives at most n events from the given subscription and writes each [Envelope] as a JSON line on w,
+// returning how many events were written. Events are Ack-ed after being written, events that could not be written
+// are Nack-ed. Like [Subscription.ReceiveN] it may dump less events if the context is canceled/deadline exceeded.
+// The written events can be published again with [Replay].
+func Dump[T any](ctx context.Context, sub *Subscription[T], w io.Writer, n int) (int, error) {
+	events, err := sub.ReceiveN(ctx, n)
+	if err != nil {
+		return 0, fmt.Errorf("receiving events: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	for i, event := range events {
+		if err := encoder.Encode(event.Envelope); err != nil {
+			for _, event := range events[i:] {
+				event.Nack()
+			}
+			return i, fmt.Errorf("writing event %d: %w", i, err)
+		}
+		event.Ack()
+	}
+	return len(events), nil
+}
+
+// Replay reads events written by [Dump] from r (one [Envelope] per line) and publishes them with the given publisher.
+// The trace, request and organization IDs of each envelope are kept on the published events.
+// It fails if an envelope has a different name than the publisher.
+func Replay[T any](ctx context.Context, pub *Publisher[T], r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	for i := 0; ; i++ {
+		var envelope Envelope[T]
+		if err := decoder.Decode(&envelope); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("reading event %d: %w", i, err)
+		}
+		if envelope.Name != pub.Name() {
+			return fmt.Errorf("event %d: name %q doesn't match publisher name %q", i, envelope.Name, pub.Name())
+		}
+		eventCtx := tracing.CtxWithTraceID(ctx, envelope.TraceID)
+		eventCtx = tracing.CtxWithOrgID(eventCtx, envelope.OrgID)
+		eventCtx = tracing.CtxWithRequestID(eventCtx, envelope.RequestID)
+		if err := pub.Publish(eventCtx, envelope.Event); err != nil {
+			return fmt.Errorf("publishing event %d: %w", i, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package event_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"github.com/birdie-ai/golibs/tracing"
+	"gocloud.dev/pubsub"
+)
+
+func TestDumpReplay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+
+	var (
+		publishers    []*event.Publisher[int]
+		subscriptions []*event.Subscription[int]
+	)
+	for _, suffix := range []string{"-dump", "-replay"} {
+		url := newTopicURL(t) + suffix
+		topic, err := pubsub.OpenTopic(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shutdown(t, topic)
+
+		subscription, err := event.NewSubscription[int](eventName, url, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shutdown(t, subscription)
+
+		publishers = append(publishers, event.NewPublisher[int](eventName, topic))
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	want := map[int]event.Envelope[int]{}
+	for i, traceID := range []string{"trace-1", "trace-2"} {
+		requestID := "request-" + traceID
+		envelope := event.Envelope[int]{TraceID: traceID, RequestID: requestID, OrgID: "org", Name: eventName, Event: i}
+		publishCtx := tracing.CtxWithOrgID(tracing.CtxWithTraceID(ctx, traceID), "org")
+		publishCtx = tracing.CtxWithRequestID(publishCtx, requestID)
+		if err := publishers[0].Publish(publishCtx, envelope.Event); err != nil {
+			t.Fatal(err)
+		}
+		want[envelope.Event] = envelope
+	}
+
+	var dump bytes.Buffer
+	dumpCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	n, err := event.Dump(dumpCtx, subscriptions[0], &dump, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, n, 2)
+	assertEqual(t, strings.Count(dump.String(), "\n"), 2)
+
+	// The tracing information of the replayed events is kept, not the one from the replay context
+	replayCtx := tracing.CtxWithRequestID(ctx, "replay-request")
+	if err := event.Replay(replayCtx, publishers[1], &dump); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delivery order is not guaranteed
+	got := map[int]event.Envelope[int]{}
+	for range 2 {
+		e, err := subscriptions[1].Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Ack()
+		got[e.Event] = e.Envelope
+	}
+	assertEqual(t, got, want)
+}
+
+func TestReplayFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	topic, err := pubsub.OpenTopic(ctx, newTopicURL(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	publisher := event.NewPublisher[int]("test", topic)
+
+	for _, dump := range []string{
+		`{"name":"other","event":1}` + "\n",
+		`{"name":"test","event":"not int"}` + "\n",
+		"not json\n",
+	} {
+		if err := event.Replay(ctx, publisher, strings.NewReader(dump)); err == nil {
+			t.Errorf("replaying %q: want error, got nil", dump)
+		}
+	}
+}
@@ -2,10 +2,12 @@
 package event
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"runtime"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/birdie-ai/golibs/slog"
 	"github.com/birdie-ai/golibs/tracing"
 	"github.com/google/uuid"
+	"github.com/sourcegraph/conc/pool"
 	"gocloud.dev/pubsub"
 )
 
@@ -22,6 +25,16 @@ type (
 	Publisher[T any] struct {
 		name  string
 		topic *pubsub.Topic
+		opts  publisherOptions
+	}
+
+	// PublisherOption is used to configure publishers created with [NewPublisher].
+	PublisherOption func(*publisherOptions)
+
+	publisherOptions struct {
+		compressionThreshold int
+		contextAttributes    func(context.Context) map[string]string
+		batchMaxConcurrency  int
 	}
 
 	// Event represents the structure of all data that wraps all events, like the [Envelope], but
@@ -35,16 +48,31 @@ type (
 	// Envelope represents the structure of all data that wraps all events.
 	Envelope[T any] struct {
 		TraceID string `json:"trace_id,omitempty"`
-		OrgID   string `json:"organization_id"`
-		Name    string `json:"name"`
-		Event   T      `json:"event"`
+		// RequestID is the ID of the request that originated the event (if any), see [tracing.CtxWithRequestID].
+		RequestID string `json:"request_id,omitempty"`
+		OrgID     string `json:"organization_id"`
+		Name      string `json:"name"`
+		Event     T      `json:"event"`
 	}
 
 	// Subscription is a subscription that received only specific types of events
 	// defined by [T].
 	Subscription[T any] struct {
-		name   string
-		rawsub *MessageSubscription
+		name        string
+		rawsub      *MessageSubscription
+		opts        subscriptionOptions
+		middlewares []Middleware[T]
+	}
+
+	// SubscriptionOption is used to configure subscriptions created with [NewSubscription].
+	SubscriptionOption func(*subscriptionOptions)
+
+	subscriptionOptions struct {
+		traceIDGenerator func() string
+		ackOnPanic       bool
+		strictDecoding   bool
+		// maxDecompressedSize is the max size of decompressed event bodies.
+		maxDecompressedSize int
 	}
 
 	// Handler is responsible for handling events from a [Subscription].
@@ -58,6 +86,10 @@ type (
 	// and extra metadata that is more event specific as defined by [Metadata].
 	HandlerWithMetadata[T any] func(context.Context, T, Metadata) error
 
+	// HandlerWithRaw is responsible for handling events from a [Subscription] like [HandlerWithMetadata], but it also
+	// receives the raw message body of the event (the whole [Envelope] as received), like for forwarding or hashing it.
+	HandlerWithRaw[T any] func(ctx context.Context, event T, raw []byte, metadata Metadata) error
+
 	// Message represents a raw message received on a subscription.
 	Message struct {
 		Body     []byte
@@ -73,24 +105,83 @@ type (
 		PublishedTime time.Time
 		// Attributes are defined by the publisher, like Google Cloud Pub Sub attributes (or similar concepts in other brokers).
 		Attributes map[string]string
+		// DeliveryAttempt is how many times the event broker tried to deliver this event, starting at 1 (if any).
+		// It is zero if the event broker doesn't provide it. For Google Cloud Pub Sub it is only
+		// available on subscriptions with a dead letter policy.
+		DeliveryAttempt int
 	}
 
 	// MessageSubscription represents a subscription that delivers messages as is.
 	// No assumptions are made about the message contents. This should rarely be used in favor of [Subscription].
 	MessageSubscription struct {
-		sub            *pubsub.Subscription
-		maxConcurrency int
+		sub         *pubsub.Subscription
+		url         string
+		name        string
+		concurrency *semaphore
+		pause       *gate
+		handlers    *handlerGroup
+		ackOnPanic  bool
+		stats       subscriptionStats
 	}
 
 	// MessageHandler is responsible for handling messages from a [MessageSubscription].
 	MessageHandler func(Message) error
 )
 
+// PublishBatchMaxConcurrency is the default max amount of events published concurrently by [Publisher.PublishBatch].
+const PublishBatchMaxConcurrency = 16
+
+// ErrPermanent can be used by handlers to mark errors as permanent, like events with bad data that will never be
+// handled successfully. Messages that failed with permanent errors are logged and Acked instead of Nacked,
+// so they are not redelivered (and retried) forever. Use [Permanent] to mark errors.
+var ErrPermanent = errors.New("permanent error")
+
+// Permanent marks the given error as permanent, so it matches [ErrPermanent] with [errors.Is].
+// It returns nil if the given error is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
 // NewPublisher creates a new event publisher for the given event name and topic.
-func NewPublisher[T any](name string, t *pubsub.Topic) *Publisher[T] {
+// It panics if name is empty, since all events would be published without a name (which is always a mistake).
+func NewPublisher[T any](name string, t *pubsub.Topic, options ...PublisherOption) *Publisher[T] {
+	if name == "" {
+		panic("event: creating publisher: event name can't be empty")
+	}
+	opts := publisherOptions{
+		batchMaxConcurrency: PublishBatchMaxConcurrency,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
 	return &Publisher[T]{
 		name:  name,
 		topic: t,
+		opts:  opts,
+	}
+}
+
+// PublisherWithContextAttributes configures the publisher to add the attributes computed from the context by the given
+// function on all published events, like propagating a correlation ID stored on the context.
+// They are merged with the attributes given to [Publisher.PublishWithAttrs], which take precedence when both
+// have the same attribute, so callers can always override the attributes computed from the context.
+func PublisherWithContextAttributes(attributes func(ctx context.Context) map[string]string) PublisherOption {
+	return func(o *publisherOptions) {
+		o.contextAttributes = attributes
+	}
+}
+
+// PublisherWithBatchMaxConcurrency configures the max amount of events published concurrently by [Publisher.PublishBatch].
+// If not defined it will default to [PublishBatchMaxConcurrency]. It panics if n <= 0.
+func PublisherWithBatchMaxConcurrency(n int) PublisherOption {
+	if n <= 0 {
+		panic(fmt.Errorf("batch max concurrency must be > 0: %d", n))
+	}
+	return func(o *publisherOptions) {
+		o.batchMaxConcurrency = n
 	}
 }
 
@@ -106,43 +197,233 @@ func (p *Publisher[T]) Publish(ctx context.Context, event T) error {
 
 // PublishWithAttrs will publish the given event with the provided attributes.
 // The attributes will be available when receiving the events as [Metadata.Attributes].
+// See [PublisherWithContextAttributes] for how the given attributes are merged with the ones from the context.
 func (p *Publisher[T]) PublishWithAttrs(ctx context.Context, event T, attributes map[string]string) error {
-	body := Envelope[T]{
-		TraceID: tracing.CtxGetTraceID(ctx),
-		OrgID:   tracing.CtxGetOrgID(ctx),
-		Name:    p.name,
-		Event:   event,
-	}
+	_, err := p.publish(ctx, event, attributes)
+	return err
+}
+
+// PublishAndGetID will publish the given event, like [Publisher.Publish], returning the ID assigned to the message
+// by the broker, useful to correlate events across systems.
+// The ID is empty if the broker doesn't provide it, for now only Google Cloud Pub Sub does.
+func (p *Publisher[T]) PublishAndGetID(ctx context.Context, event T) (string, error) {
+	return p.publish(ctx, event, nil)
+}
 
-	encBody, err := json.Marshal(body)
+func (p *Publisher[T]) publish(ctx context.Context, event T, attributes map[string]string) (string, error) {
+	encBody, err := json.Marshal(newEnvelope(ctx, p.name, event))
 	if err != nil {
-		return err
+		return "", err
+	}
+	attributes = p.opts.mergeContextAttributes(ctx, attributes)
+	uncompressedSize := len(encBody)
+	encBody, attributes, err = p.opts.compressBody(encBody, attributes)
+	if err != nil {
+		return "", err
 	}
 
+	var msgID string
 	start := time.Now()
 	err = p.topic.Send(ctx, &pubsub.Message{
 		Body:     encBody,
 		Metadata: attributes,
+		AfterSend: func(asFunc func(any) bool) error {
+			// Only Google Cloud Pub Sub provides the message ID for now, other brokers don't set it.
+			_ = asFunc(&msgID)
+			return nil
+		},
 	})
 	elapsed := time.Since(start)
 
-	samplePublish(p.name, elapsed, len(encBody), err)
+	samplePublish(p.name, elapsed, len(encBody), uncompressedSize, err)
 
-	return err
+	return msgID, err
+}
+
+// mergeContextAttributes returns the given attributes merged with the attributes computed from the context (if configured).
+// The given attributes take precedence and they are never modified, a new map is created if needed.
+func (o publisherOptions) mergeContextAttributes(ctx context.Context, attributes map[string]string) map[string]string {
+	if o.contextAttributes == nil {
+		return attributes
+	}
+	ctxAttributes := o.contextAttributes(ctx)
+	if len(ctxAttributes) == 0 {
+		return attributes
+	}
+	merged := make(map[string]string, len(ctxAttributes)+len(attributes))
+	maps.Copy(merged, ctxAttributes)
+	maps.Copy(merged, attributes)
+	return merged
+}
+
+// newEnvelope creates the envelope of the given event, with the tracing information of the given context.
+func newEnvelope[T any](ctx context.Context, name string, event T) Envelope[T] {
+	return Envelope[T]{
+		TraceID:   tracing.CtxGetTraceID(ctx),
+		RequestID: tracing.CtxGetRequestID(ctx),
+		OrgID:     tracing.CtxGetOrgID(ctx),
+		Name:      name,
+		Event:     event,
+	}
+}
+
+// PublishBatch will publish all the given events concurrently (at most [PublishBatchMaxConcurrency] at a time,
+// see [PublisherWithBatchMaxConcurrency]). Each event is published on its own message, like with [Publisher.Publish].
+// It returns the errors of each event, aligned with the given events, so errs[i] is the error publishing events[i]
+// (nil if it was published successfully). Failures don't stop the publishing of other events.
+// Use [JoinBatchErrors] to get a single error describing all failures.
+func (p *Publisher[T]) PublishBatch(ctx context.Context, events []T) []error {
+	return p.PublishBatchWithAttrs(ctx, events, nil)
+}
+
+// PublishBatchWithAttrs will publish all the given events with the provided attributes, like [Publisher.PublishBatch].
+// All events have the same attributes.
+func (p *Publisher[T]) PublishBatchWithAttrs(ctx context.Context, events []T, attributes map[string]string) []error {
+	errs := make([]error, len(events))
+	workers := pool.New().WithMaxGoroutines(p.opts.batchMaxConcurrency)
+
+	for i, v := range events {
+		event := v
+
+		workers.Go(func() {
+			errs[i] = p.PublishWithAttrs(ctx, event, attributes)
+		})
+	}
+	workers.Wait()
+
+	return errs
+}
+
+// JoinBatchErrors joins the errors returned by [Publisher.PublishBatch] in a single error, where each error
+// includes the index of the event that failed. It returns nil if all events were published successfully.
+func JoinBatchErrors(errs []error) error {
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("event %d: %w", i, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("publishing batch: %d of %d events failed: %w", len(failed), len(errs), errors.Join(failed...))
+}
+
+// DecodeEnvelope decodes the given message body as an [Envelope] without decoding the event itself,
+// which is kept as raw JSON. Useful for tooling that needs to inspect the envelope metadata of any event,
+// like dead-letter inspection or generic routing of events.
+func DecodeEnvelope(body []byte) (Envelope[json.RawMessage], error) {
+	var envelope Envelope[json.RawMessage]
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return Envelope[json.RawMessage]{}, fmt.Errorf("decoding event envelope: %w", err)
+	}
+	return envelope, nil
 }
 
 // NewSubscription creates a subscription that will accept on events of the given type and name.
-func NewSubscription[T any](name, url string, maxConcurrency int) (*Subscription[T], error) {
+// It returns an error if name is empty, since all events would be discarded as having the wrong name.
+func NewSubscription[T any](name, url string, maxConcurrency int, options ...SubscriptionOption) (*Subscription[T], error) {
+	if name == "" {
+		return nil, errors.New("event name can't be empty")
+	}
 	rawsub, err := NewRawSubscription(url, maxConcurrency)
 	if err != nil {
 		return nil, err
 	}
+	return NewSubscriptionFromRaw[T](name, rawsub, options...)
+}
+
+// NewSubscriptionFromRaw creates a subscription that will accept events of the given type and name from the given
+// raw subscription, like one created with [NewRawSubscriptionFrom]. The raw subscription is owned by the returned
+// subscription, it should not be used directly anymore.
+// It returns an error if name is empty, since all events would be discarded as having the wrong name.
+func NewSubscriptionFromRaw[T any](name string, rawsub *MessageSubscription, options ...SubscriptionOption) (*Subscription[T], error) {
+	if name == "" {
+		return nil, errors.New("event name can't be empty")
+	}
+	// Used only for metrics, raw subscriptions have no event name
+	rawsub.name = name
+	opts := subscriptionOptions{
+		traceIDGenerator:    uuid.NewString,
+		maxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	rawsub.ackOnPanic = opts.ackOnPanic
 	return &Subscription[T]{
 		name:   name,
 		rawsub: rawsub,
+		opts:   opts,
 	}, nil
 }
 
+// SubscriptionWithTraceIDGenerator configures the function used to generate trace IDs for received
+// events that have no trace ID. By default a random UUID is generated.
+// If the generator returns an empty string the event is handled with no trace ID, so a generator that
+// always returns an empty string disables trace ID generation (useful when trace IDs are provided out-of-band).
+func SubscriptionWithTraceIDGenerator(generator func() string) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.traceIDGenerator = generator
+	}
+}
+
+// SubscriptionWithAckOnPanic configures the subscription to Ack messages when the handler panics, instead of Nack-ing them.
+// Panics caused by bad data (poison messages) are deterministic, so Nack-ing would redeliver (and panic) forever.
+// Ack-ing discards the message, so it is only redelivered if the broker does so (like a dead letter policy).
+// The panic and its stack trace are always logged, by default messages are Nack-ed on panic.
+func SubscriptionWithAckOnPanic() SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.ackOnPanic = true
+	}
+}
+
+// SubscriptionWithStrictDecoding configures the subscription to reject events with unknown JSON fields, like
+// fields added by producers that are not supported by the event type yet. By default unknown fields are ignored.
+// Only the event itself ([Envelope.Event]) is checked, unknown envelope fields are always ignored, so consumers
+// keep working when new envelope fields are added.
+// Events with unknown fields are discarded as malformed (like invalid JSON events), the logged error has the unknown field.
+func SubscriptionWithStrictDecoding() SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.strictDecoding = true
+	}
+}
+
+// decodeEnvelope parses the given envelope JSON, rejecting unknown fields of the event if strict decoding is configured.
+func decodeEnvelope[T any](o subscriptionOptions, data []byte) (Envelope[T], error) {
+	var envelope Envelope[T]
+	if !o.strictDecoding {
+		err := json.Unmarshal(data, &envelope)
+		return envelope, err
+	}
+	rawEnvelope, err := DecodeEnvelope(data)
+	if err != nil {
+		return envelope, err
+	}
+	envelope = Envelope[T]{
+		TraceID:   rawEnvelope.TraceID,
+		RequestID: rawEnvelope.RequestID,
+		OrgID:     rawEnvelope.OrgID,
+		Name:      rawEnvelope.Name,
+	}
+	if len(rawEnvelope.Event) == 0 {
+		// Like with non strict decoding, a missing event is the zero value
+		return envelope, nil
+	}
+	err = o.unmarshal(rawEnvelope.Event, &envelope.Event)
+	return envelope, err
+}
+
+// unmarshal parses the given JSON data, rejecting unknown fields if strict decoding is configured.
+func (o subscriptionOptions) unmarshal(data []byte, v any) error {
+	if !o.strictDecoding {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
 // NewRawSubscription creates a new raw subscription. It provides messages in a
 // service like manner (serve) and manages concurrent execution, each message
 // is processed in its own go-routines respecting the given maxConcurrency.
@@ -155,10 +436,25 @@ func NewRawSubscription(url string, maxConcurrency int) (*MessageSubscription, e
 	if err != nil {
 		return nil, err
 	}
+	rawsub := NewRawSubscriptionFrom(sub, maxConcurrency)
+	rawsub.url = url
+	return rawsub, nil
+}
+
+// NewRawSubscriptionFrom creates a new raw subscription from an already opened subscription, like
+// [NewRawSubscription] does from an URL. Useful when the subscription driver needs to be configured
+// directly (or in tests). The given subscription is owned by the returned subscription, it is shutdown
+// by [MessageSubscription.Shutdown]. It panics if maxConcurrency <= 0.
+func NewRawSubscriptionFrom(sub *pubsub.Subscription, maxConcurrency int) *MessageSubscription {
+	if maxConcurrency <= 0 {
+		panic(fmt.Errorf("max concurrency must be > 0: %d", maxConcurrency))
+	}
 	return &MessageSubscription{
-		sub:            sub,
-		maxConcurrency: maxConcurrency,
-	}, nil
+		sub:         sub,
+		concurrency: newSemaphore(maxConcurrency),
+		pause:       newGate(),
+		handlers:    newHandlerGroup(),
+	}
 }
 
 // Name returns the name of the event.
@@ -166,8 +462,18 @@ func (s *Subscription[T]) Name() string {
 	return s.name
 }
 
+// Use adds the given middlewares to the subscription, they will wrap the handlers of all Serve methods
+// (like [Subscription.Serve] and [Subscription.ServeWithMetadata]) in the order they are registered, see [Chain].
+// Middlewares receive the same context as the handler, with the event trace/org IDs and the logger.
+// Events received with [Subscription.Receive] and [Subscription.ReceiveN] are not affected.
+// Use must be called before serving, it is not safe to call it concurrently with the Serve methods.
+func (s *Subscription[T]) Use(middlewares ...Middleware[T]) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
 // ReceiveN will receive at most N events.
 // It may return less events if the provided context is canceled/deadline exceeded.
+// On other errors the events already received are Nack-ed, so they are redelivered, and only the error is returned.
 // If called concurrently with [Subscription.Serve] it will compete for events.
 // Events returned here must be Ack-ed after the caller is done with them.
 // For simple event handling [Subscription.Serve] will be better. This method is useful
@@ -186,6 +492,67 @@ func (s *Subscription[T]) ReceiveN(ctx context.Context, n int) ([]*Event[T], err
 				return events, nil
 			}
 			// Some other error happened, normal failure then
+			nackAll(events)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// batchPollTimeout is how long [Subscription.ReceiveBatch] waits for events that are immediately available
+// after the min wait is over.
+const batchPollTimeout = 10 * time.Millisecond
+
+// BatchOpts configures how events are received by [Subscription.ReceiveBatch].
+type BatchOpts struct {
+	// Max is the max amount of events on the batch, the batch is returned as soon as it is full.
+	Max int
+	// MinWait is how long to wait for events before returning a batch that is not full.
+	// After MinWait the batch is returned as soon as it has at least one event and no more events are immediately available.
+	MinWait time.Duration
+	// MaxWait is the max time to wait for events, even if no event was received (returning an empty batch).
+	// If zero there is no max time, the wait is limited only by the context.
+	MaxWait time.Duration
+}
+
+// ReceiveBatch will receive a batch of events, waiting for events according to the given options:
+// "give me up to 100 events, but don't wait more than 500ms even if fewer arrive, and return at least 1" is
+// BatchOpts{Max: 100, MinWait: 500 * time.Millisecond}.
+// It may return less events if the provided context is canceled/deadline exceeded and it Nacks the events already
+// received on other errors, like [Subscription.ReceiveN].
+// Events returned here must be Ack-ed after the caller is done with them.
+// It panics if opts.Max <= 0.
+func (s *Subscription[T]) ReceiveBatch(ctx context.Context, opts BatchOpts) ([]*Event[T], error) {
+	if opts.Max <= 0 {
+		panic(fmt.Errorf("batch max must be > 0: %d", opts.Max))
+	}
+	start := time.Now()
+	minDeadline := start.Add(opts.MinWait)
+	maxDeadline := start.Add(opts.MaxWait)
+	if opts.MaxWait > 0 && minDeadline.After(maxDeadline) {
+		minDeadline = maxDeadline
+	}
+
+	events := []*Event[T]{}
+	for len(events) < opts.Max {
+		deadline := minDeadline
+		switch {
+		case len(events) == 0:
+			deadline = maxDeadline
+		case !time.Now().Before(minDeadline):
+			// The min wait is over, but events that are already available (like buffered ones) are still added to the batch.
+			// An expired deadline would fail the receive before checking for available events.
+			deadline = time.Now().Add(batchPollTimeout)
+		}
+		event, err := s.receiveUntil(ctx, deadline, len(events) > 0 || opts.MaxWait > 0)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) ||
+				errors.Is(err, context.Canceled) {
+				// Time window reached, returning current batch
+				return events, nil
+			}
+			nackAll(events)
 			return nil, err
 		}
 		events = append(events, event)
@@ -193,6 +560,23 @@ func (s *Subscription[T]) ReceiveN(ctx context.Context, n int) ([]*Event[T], err
 	return events, nil
 }
 
+// nackAll Nacks all the given events.
+func nackAll[T any](events []*Event[T]) {
+	for _, event := range events {
+		event.Nack()
+	}
+}
+
+// receiveUntil receives a single event like [Subscription.Receive], waiting until the given deadline (if limited).
+func (s *Subscription[T]) receiveUntil(ctx context.Context, deadline time.Time, limited bool) (*Event[T], error) {
+	if !limited {
+		return s.Receive(ctx)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	return s.Receive(ctx)
+}
+
 // Receive will receive a single event.
 // If called concurrently with [Subscription.Serve] it will compete for events.
 // Events returned here must be Ack-ed after the caller is done with them.
@@ -203,7 +587,7 @@ func (s *Subscription[T]) Receive(ctx context.Context) (*Event[T], error) {
 	if err != nil {
 		return nil, err
 	}
-	_, envelope, err := s.createEvent(m.Message)
+	_, envelope, err := s.createEvent(&m.Message)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +610,8 @@ func (e *Event[T]) Nack() {
 // Serve will start serving all events from the subscription calling handler for each
 // event. It will run until [Subscription.Shutdown] is called.
 // If the error is nil Ack is sent.
-// If a non-nil error is returned by the handler Nack will be sent.
+// If a non-nil error is returned by the handler Nack will be sent, unless it is a permanent error (see [ErrPermanent]).
+// Events compressed by publishers (see [PublisherWithBodyCompression]) are decompressed transparently.
 // If a received event is not a valid JSON it will be discarded as malformed and a Nack will be sent automatically.
 // If a received event has the wrong name it will be discarded as malformed and a Nack will be sent automatically.
 // Serve may be called multiple times, each time will start a new serving service that will
@@ -235,14 +620,14 @@ func (e *Event[T]) Nack() {
 // If the handler panics, the [Subscription] (the caller of the handler) assumes
 // that the effect of the panic was isolated to the active event handling.
 // It recovers the panic, logs a stack trace and returns an error (failing the event handling gracefully,
-// which in most event systems will trigger some form of retry).
+// which in most event systems will trigger some form of retry), unless [SubscriptionWithAckOnPanic] is used.
 func (s *Subscription[T]) Serve(handler Handler[T]) error {
 	return s.rawsub.Serve(SampledMessageHandler(s.name, func(msg Message) error {
-		ctx, event, err := s.createEvent(msg)
+		ctx, event, err := s.createEvent(&msg)
 		if err != nil {
 			return err
 		}
-		return handler(ctx, event.Event)
+		return s.handle(ctx, event.Event, handler)
 	}))
 }
 
@@ -250,52 +635,122 @@ func (s *Subscription[T]) Serve(handler Handler[T]) error {
 // event, providing both the event and any metadata associated with it.
 // It will run until [Subscription.Shutdown] is called.
 // If the error is nil Ack is sent.
-// If a non-nil error is returned by the handler Nack will be sent.
+// If a non-nil error is returned by the handler Nack will be sent, unless it is a permanent error (see [ErrPermanent]).
+// Events compressed by publishers (see [PublisherWithBodyCompression]) are decompressed transparently.
 // If a received event is not a valid JSON it will be discarded as malformed and a Nack will be sent automatically.
 // If a received event has the wrong name it will be discarded as malformed and a Nack will be sent automatically.
 // ServeWithMetadata may be called multiple times, each time will start a new serving service that will
 // run up to "maxConcurrency" go-routines.
 func (s *Subscription[T]) ServeWithMetadata(handler HandlerWithMetadata[T]) error {
 	return s.rawsub.Serve(SampledMessageHandler(s.name, func(msg Message) error {
-		ctx, event, err := s.createEvent(msg)
+		ctx, event, err := s.createEvent(&msg)
 		if err != nil {
 			return err
 		}
-		return handler(ctx, event.Event, msg.Metadata)
+		return s.handle(ctx, event.Event, func(ctx context.Context, event T) error {
+			return handler(ctx, event, msg.Metadata)
+		})
 	}))
 }
 
-func (s *Subscription[T]) createEvent(msg Message) (context.Context, Envelope[T], error) {
+// ServeWithRaw will start serving all events from the subscription like [Subscription.ServeWithMetadata], but
+// the handler also receives the raw message body of each event, avoiding re-encoding the event to get its bytes.
+// Compressed events are provided decompressed, so the raw body is always the JSON [Envelope].
+// The raw body must not be modified by the handler.
+func (s *Subscription[T]) ServeWithRaw(handler HandlerWithRaw[T]) error {
+	return s.rawsub.Serve(SampledMessageHandler(s.name, func(msg Message) error {
+		ctx, event, err := s.createEvent(&msg)
+		if err != nil {
+			return err
+		}
+		return s.handle(ctx, event.Event, func(ctx context.Context, event T) error {
+			return handler(ctx, event, msg.Body, msg.Metadata)
+		})
+	}))
+}
+
+// ServeWithFilter will start serving events from the subscription like [Subscription.Serve], but only
+// messages with [Metadata] accepted by the given filter are handled. Messages rejected by the filter
+// are Acked and skipped before being parsed, avoiding the cost of parsing events that would be discarded.
+// It is useful when subscribing to a topic shared by many different consumers and only messages with
+// specific attributes (see [Publisher.PublishWithAttrs]) are relevant.
+// Skipped messages are not sampled as processed events, they are counted by the `event_process_skipped_total` metric.
+func (s *Subscription[T]) ServeWithFilter(filter func(Metadata) bool, handler Handler[T]) error {
+	sampledHandler := SampledMessageHandler(s.name, func(msg Message) error {
+		ctx, event, err := s.createEvent(&msg)
+		if err != nil {
+			return err
+		}
+		return s.handle(ctx, event.Event, handler)
+	})
+	return s.rawsub.Serve(func(msg Message) error {
+		if !filter(msg.Metadata) {
+			sampleSkipped(s.name)
+			return nil
+		}
+		return sampledHandler(msg)
+	})
+}
+
+// handle calls the given handler wrapped with the subscription middlewares (see [Subscription.Use]).
+func (s *Subscription[T]) handle(ctx context.Context, event T, handler Handler[T]) error {
+	return Chain(handler, s.middlewares...)(ctx, event)
+}
+
+// createEvent parses the event on the given message. Compressed messages are decompressed, replacing the message body.
+func (s *Subscription[T]) createEvent(msg *Message) (context.Context, Envelope[T], error) {
 	var event Envelope[T]
 
 	log := slog.Default()
 
-	if err := json.Unmarshal(msg.Body, &event); err != nil {
+	body, err := decompressBody(*msg, s.opts.maxDecompressedSize)
+	if err != nil {
+		s.rawsub.stats.malformed.Add(1)
+		log.Error("decompressing event body", "name", s.name, "error", err, "metadata", msg.Metadata)
+		return nil, event, err
+	}
+	msg.Body = body
+
+	event, err = decodeEnvelope[T](s.opts, msg.Body)
+	if err != nil {
+		s.rawsub.stats.malformed.Add(1)
 		log.Error("parsing event body", "name", s.name, "error", err, "body", string(msg.Body))
 		return nil, event, fmt.Errorf("parsing event as JSON, event: %v, error: %v", msg, err)
 	}
 
 	if event.Name != s.name {
+		s.rawsub.stats.malformed.Add(1)
 		log.Error("event name doesn't match handler", "expected", s.name, "received", event.Name)
 		return nil, event, fmt.Errorf("event name doesn't match %q: event: %v", s.name, msg)
 	}
 
 	if event.TraceID == "" {
-		event.TraceID = uuid.NewString()
+		event.TraceID = s.opts.traceIDGenerator()
 	}
+	return newEventContext(event.TraceID, event.OrgID, event.RequestID), event, nil
+}
 
-	log = log.With("request_id", uuid.NewString())
-	log = log.With("trace_id", event.TraceID)
-	log = log.With("organization_id", event.OrgID)
-
+// newEventContext creates the context used to handle an event with the given tracing information.
+// A new request ID is generated if the event has none.
+func newEventContext(traceID, orgID, requestID string) context.Context {
 	ctx := context.Background()
-	ctx = tracing.CtxWithTraceID(ctx, event.TraceID)
-	ctx = tracing.CtxWithOrgID(ctx, event.OrgID)
-	ctx = slog.NewContext(ctx, log)
-	return ctx, event, nil
+	ctx = tracing.CtxWithTraceID(ctx, traceID)
+	ctx = tracing.CtxWithOrgID(ctx, orgID)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	ctx = tracing.CtxWithRequestID(ctx, requestID)
+	return slog.NewContext(ctx, slog.WithContextFields(ctx))
+}
+
+// SetMaxConcurrency changes the max amount of events handled concurrently by [Subscription.Serve].
+// See [MessageSubscription.SetMaxConcurrency] for details.
+func (s *Subscription[T]) SetMaxConcurrency(n int) {
+	s.rawsub.SetMaxConcurrency(n)
 }
 
 // Shutdown will shutdown the subscriber, stopping any calls to [Subscription.Serve].
+// Events being handled are waited for, see [MessageSubscription.Shutdown] for details.
 // The subscription should not be used after this method is called.
 func (s *Subscription[T]) Shutdown(ctx context.Context) error {
 	return s.rawsub.Shutdown(ctx)
@@ -304,7 +759,8 @@ func (s *Subscription[T]) Shutdown(ctx context.Context) error {
 // Serve will start serving all messages from the subscription calling handler for each
 // message. It will run until [MessageSubscription.Shutdown] is called.
 // If the error is nil Ack is sent.
-// If a non-nil error is returned by the handler then a Nack will be sent.
+// If a non-nil error is returned by the handler then a Nack will be sent, unless it is a permanent error
+// (see [ErrPermanent]), which is logged and Acked. Handlers can delay the redelivery with [RetryAfter].
 // Serve may be called multiple times, each time will start a new serving service that will
 // run up to "maxConcurrency" go-routines.
 //
@@ -313,49 +769,113 @@ func (s *Subscription[T]) Shutdown(ctx context.Context) error {
 // It recovers the panic, logs a stack trace and returns an error (failing the event handling gracefully,
 // which in most event systems will trigger some form of retry).
 func (r *MessageSubscription) Serve(handler MessageHandler) error {
-	semaphore := make(chan struct{}, r.maxConcurrency)
 	for {
-		semaphore <- struct{}{}
-		rmsg, err := r.receive(context.Background())
+		rmsg, err := r.receiveNext()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer r.concurrency.release()
+			defer r.handlers.done()
+
+			r.handle(rmsg, handler)
+		}()
+	}
+}
+
+// receiveNext waits for a free concurrency slot (and for the subscription to be resumed) and receives the next message.
+// After handling the message the slot must be released and the handler marked as done (see [handlerGroup]).
+func (r *MessageSubscription) receiveNext() (*message, error) {
+	for {
+		r.pause.wait()
+		waitStart := time.Now()
+		r.concurrency.acquire()
+		sampleSlotWait(r.name, time.Since(waitStart))
+		rmsg, err := r.receive(r.handlers.receiveCtx)
 		if err != nil {
+			r.concurrency.release()
 			// From: https://pkg.go.dev/gocloud.dev@v0.30.0/pubsub#example-Subscription.Receive-Concurrent
 			// Errors from Receive indicate that Receive will no longer succeed.
-			return fmt.Errorf("receive from subscription failed, stopping serving: %v", err)
+			return nil, fmt.Errorf("receive from subscription failed, stopping serving: %v", err)
 		}
-		go func() {
-			defer func() {
-				<-semaphore
-			}()
-
-			defer func() {
-				if err := recover(); err != nil {
-					// 64KB, if it is good enough for Go's standard lib it is good enough for us :-)
-					const size = 64 << 10
-					buf := make([]byte, size)
-					buf = buf[:runtime.Stack(buf, false)]
-					slog.Error("panic: message subscription: handling message",
-						"error", err,
-						"message_body", rmsg.Body,
-						"metadata", rmsg.Metadata,
-						"stack_trace", string(buf))
-					rmsg.Nack()
-				}
-			}()
-
-			err := handler(rmsg.Message)
-			if err != nil {
-				rmsg.Nack()
+		if !r.handlers.start() {
+			// Received while shutting down, the next receive fails since receiving was stopped.
+			r.concurrency.release()
+			rmsg.Nack()
+			continue
+		}
+		return rmsg, nil
+	}
+}
+
+// handle calls the handler with the given message, sending Ack/Nack according to the result (recovering panics).
+// It returns true if the message was Acked.
+func (r *MessageSubscription) handle(rmsg *message, handler MessageHandler) (acked bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			r.stats.panics.Add(1)
+			// 64KB, if it is good enough for Go's standard lib it is good enough for us :-)
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			slog.Error("panic: message subscription: handling message",
+				"error", err,
+				"message_body", rmsg.Body,
+				"metadata", rmsg.Metadata,
+				"ack", r.ackOnPanic,
+				"stack_trace", string(buf))
+			if r.ackOnPanic {
+				rmsg.Ack()
+				acked = true
 				return
 			}
+			rmsg.Nack()
+			acked = false
+		}
+	}()
+
+	err := handler(rmsg.Message)
+	if err != nil {
+		if errors.Is(err, ErrPermanent) {
+			slog.Error("message subscription: discarding message with permanent error",
+				"error", err,
+				"metadata", rmsg.Metadata)
 			rmsg.Ack()
-		}()
+			return true
+		}
+		if delay, ok := retryAfterDelay(err); ok {
+			r.nackAfter(rmsg, delay)
+			return false
+		}
+		rmsg.Nack()
+		return false
 	}
+	rmsg.Ack()
+	return true
+}
+
+// SetMaxConcurrency changes the max amount of messages handled concurrently by [MessageSubscription.Serve].
+// It is safe to call while serving. If the max concurrency is reduced messages being handled are not affected,
+// new messages will only be handled after the amount of messages being handled drops below the new max concurrency.
+// It panics if n <= 0.
+func (r *MessageSubscription) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		panic(fmt.Errorf("max concurrency must be > 0: %d", n))
+	}
+	r.concurrency.setLimit(n)
 }
 
 // Shutdown will shutdown the subscriber, stopping any calls to [MessageSubscription.Serve].
+// It stops receiving new messages and waits for the handlers that are running to finish (and Ack/Nack their messages)
+// before shutting down the underlying subscription, so deploys don't interrupt messages being handled.
+// If the given context is done before the handlers finish an error with the amount of handlers still running
+// is returned, the underlying subscription is shutdown anyway.
 // The subscription should not be used after this method is called.
 func (r *MessageSubscription) Shutdown(ctx context.Context) error {
-	return r.sub.Shutdown(ctx)
+	// Paused serving must also stop
+	r.pause.stop()
+	drainErr := r.handlers.stop(ctx)
+	return errors.Join(drainErr, r.sub.Shutdown(ctx))
 }
 
 func (r *MessageSubscription) receive(ctx context.Context) (*message, error) {
@@ -363,43 +883,51 @@ func (r *MessageSubscription) receive(ctx context.Context) (*message, error) {
 	if err != nil {
 		return nil, err
 	}
-	id, publishedTime := getMetadata(gocloudMsg)
+	r.stats.received.Add(1)
 	return &message{
 		Message: Message{
-			Body: gocloudMsg.Body,
-			Metadata: Metadata{
-				ID:            id,
-				PublishedTime: publishedTime,
-				Attributes:    gocloudMsg.Metadata,
-			},
+			Body:     gocloudMsg.Body,
+			Metadata: getMetadata(gocloudMsg),
 		},
-		msg: gocloudMsg,
+		msg:   gocloudMsg,
+		stats: &r.stats,
 	}, nil
 }
 
 type message struct {
 	Message
-	msg *pubsub.Message
+	msg   *pubsub.Message
+	stats *subscriptionStats
 }
 
-// Nack this msg (if possible).
+// Nack this msg (if possible), only messages that are actually Nacked are counted on the stats.
 func (r *message) Nack() {
 	if r.msg.Nackable() {
+		r.stats.nacked.Add(1)
 		r.msg.Nack()
 	}
 }
 
 // Ack this msg.
 func (r *message) Ack() {
+	r.stats.acked.Add(1)
 	r.msg.Ack()
 }
 
-func getMetadata(msg *pubsub.Message) (string, time.Time) {
+func getMetadata(msg *pubsub.Message) Metadata {
+	metadata := Metadata{
+		Attributes: msg.Metadata,
+	}
 	// This is the only way to get broker specific metadata
 	// For now we only support Google Cloud.
 	var pbmsg *pubsubpb.PubsubMessage
 	if msg.As(&pbmsg) {
-		return pbmsg.MessageId, pbmsg.PublishTime.AsTime()
+		metadata.ID = pbmsg.MessageId
+		metadata.PublishedTime = pbmsg.PublishTime.AsTime()
+	}
+	var pbrecvmsg *pubsubpb.ReceivedMessage
+	if msg.As(&pbrecvmsg) {
+		metadata.DeliveryAttempt = int(pbrecvmsg.DeliveryAttempt)
 	}
-	return "", time.Time{}
+	return metadata
 }
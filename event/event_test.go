@@ -3,11 +3,13 @@ package event_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
 	"github.com/birdie-ai/golibs/event"
 	"github.com/birdie-ai/golibs/tracing"
 	"github.com/google/go-cmp/cmp"
@@ -43,6 +45,7 @@ func TestPublishEvent(t *testing.T) {
 		eventName = "test"
 		fieldData = "some data"
 		traceID   = "trace-id"
+		requestID = "request-id"
 		orgID     = "org-id"
 	)
 
@@ -54,6 +57,7 @@ func TestPublishEvent(t *testing.T) {
 	go func() {
 		// Tracing info stored on the context is propagated to the events.
 		ctx := tracing.CtxWithTraceID(ctx, traceID)
+		ctx = tracing.CtxWithRequestID(ctx, requestID)
 		ctx = tracing.CtxWithOrgID(ctx, orgID)
 
 		err := publisher.Publish(ctx, wantEvt)
@@ -67,10 +71,11 @@ func TestPublishEvent(t *testing.T) {
 	gotMsg.Ack()
 
 	want := event.Envelope[Event]{
-		TraceID: traceID,
-		OrgID:   orgID,
-		Name:    eventName,
-		Event:   wantEvt,
+		TraceID:   traceID,
+		RequestID: requestID,
+		OrgID:     orgID,
+		Name:      eventName,
+		Event:     wantEvt,
 	}
 	got := event.Envelope[Event]{}
 	if err := json.Unmarshal(gotMsg.Body, &got); err != nil {
@@ -131,6 +136,61 @@ func TestPublishEventWithoutTracingInfo(t *testing.T) {
 	}
 }
 
+func TestPublisherWithContextAttributes(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := pubsub.OpenSubscription(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[int]("test", topic, event.PublisherWithContextAttributes(func(ctx context.Context) map[string]string {
+		return map[string]string{
+			"correlation_id": tracing.CtxGetTraceID(ctx),
+			"source":         "context",
+		}
+	}))
+
+	ctx = tracing.CtxWithTraceID(ctx, "trace-id")
+	attrs := map[string]string{"source": "caller"}
+	if err := publisher.Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishWithAttrs(ctx, 2, attrs); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, attrs, map[string]string{"source": "caller"})
+
+	// Delivery order is not guaranteed
+	got := map[int]map[string]string{}
+	for range 2 {
+		msg, err := subscription.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.Ack()
+		var envelope event.Envelope[int]
+		if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		got[envelope.Event] = msg.Metadata
+	}
+	assertEqual(t, got, map[int]map[string]string{
+		1: {"correlation_id": "trace-id", "source": "context"},
+		2: {"correlation_id": "trace-id", "source": "caller"},
+	})
+}
+
 func TestSubscriptionServing(t *testing.T) {
 	t.Parallel()
 
@@ -385,6 +445,188 @@ func TestSubscriptionReceiveNError(t *testing.T) {
 	}
 }
 
+func TestSubscriptionReceiveNErrorNacksReceivedEvents(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatalf("creating subscription: %v", err)
+	}
+	defer shutdown(t, subscription)
+
+	if err := event.NewPublisher[int](eventName, topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		events []*event.Event[int]
+		err    error
+	}
+	received := make(chan result)
+	go func() {
+		events, err := subscription.ReceiveN(ctx, 2)
+		received <- result{events, err}
+	}()
+
+	// The malformed message is sent only after the event is received, so it fails the second receive.
+	for subscription.Stats().Received < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte("malformed")}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-received
+	if res.err == nil {
+		t.Fatalf("got no error and %v; want error", res.events)
+	}
+	assertEqual(t, subscription.Stats().Nacked, uint64(1))
+
+	// The Nacked event is redelivered.
+	got, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Ack()
+	assertEqual(t, got.Event, 1)
+}
+
+func TestSubscriptionReceiveBatch(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const (
+		eventName   = "test"
+		totalEvents = 5
+	)
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	// Events are published before receiving, so they are received without waiting for the broker to poll.
+	publisher := event.NewPublisher[int](eventName, topic)
+	for i := range totalEvents - 1 {
+		if err := publisher.Publish(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Full batches are returned right away, even with a long min wait
+	full, err := subscription.ReceiveBatch(ctx, event.BatchOpts{Max: 2, MinWait: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(full), 2)
+
+	var got []int
+	for _, e := range full {
+		got = append(got, e.Event)
+		e.Ack()
+	}
+	for len(got) < totalEvents-1 {
+		batch, err := subscription.ReceiveBatch(ctx, event.BatchOpts{Max: 10, MinWait: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) == 0 {
+			t.Fatal("got empty batch; want at least one event")
+		}
+		for _, e := range batch {
+			got = append(got, e.Event)
+			e.Ack()
+		}
+	}
+
+	// When no events are available the max wait is respected
+	empty, err := subscription.ReceiveBatch(ctx, event.BatchOpts{Max: 10, MaxWait: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(empty), 0)
+
+	// Without max wait at least one event is returned, even after the min wait
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		if err := publisher.Publish(ctx, totalEvents-1); err != nil {
+			t.Errorf("publishing: %v", err)
+		}
+	}()
+	last, err := subscription.ReceiveBatch(ctx, event.BatchOpts{Max: 10, MinWait: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(last), 1)
+	last[0].Ack()
+	got = append(got, last[0].Event)
+
+	// There are no order guarantees
+	sort.Ints(got)
+	assertEqual(t, got, []int{0, 1, 2, 3, 4})
+}
+
+func TestSubscriptionReceiveBatchAvailableEvents(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const (
+		eventName   = "test"
+		totalEvents = 5
+	)
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	for i := range totalEvents {
+		if err := publisher.Publish(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Events already available are all returned, even without a min wait.
+	batch, err := subscription.ReceiveBatch(ctx, event.BatchOpts{Max: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []int{}
+	for _, e := range batch {
+		got = append(got, e.Event)
+		e.Ack()
+	}
+	sort.Ints(got)
+	assertEqual(t, got, []int{0, 1, 2, 3, 4})
+}
+
 func TestSubscriptionRecoversFromPanic(t *testing.T) {
 	t.Parallel()
 
@@ -696,6 +938,7 @@ func TestSubscriptionServingWithMetadata(t *testing.T) {
 	var zeroTime time.Time
 	assertEqual(t, gotMetadata.PublishedTime, zeroTime)
 	assertEqual(t, gotMetadata.ID, "")
+	assertEqual(t, gotMetadata.DeliveryAttempt, 0)
 }
 
 func TestRawSubscriptionServingWithMetadata(t *testing.T) {
@@ -743,33 +986,786 @@ func TestRawSubscriptionServingWithMetadata(t *testing.T) {
 	var zeroTime time.Time
 	assertEqual(t, gotMsg.Metadata.PublishedTime, zeroTime)
 	assertEqual(t, gotMsg.Metadata.ID, "")
+	assertEqual(t, gotMsg.Metadata.DeliveryAttempt, 0)
 }
 
-type shutdowner interface {
-	Shutdown(context.Context) error
+func TestSubscriptionServingWithFilter(t *testing.T) {
+	t.Parallel()
+
+	type Event struct {
+		ID int `json:"id"`
+	}
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[Event](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are published before serving, so they are received without waiting for the broker to poll.
+	publisher := event.NewPublisher[Event](eventName, topic)
+	if err := publisher.PublishWithAttrs(ctx, Event{ID: 1}, map[string]string{"region": "eu"}); err != nil {
+		t.Fatal(err)
+	}
+	// Skipped messages are not parsed, so even invalid ones are just skipped.
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte("invalid"), Metadata: map[string]string{"region": "eu"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishWithAttrs(ctx, Event{ID: 2}, map[string]string{"region": "us"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gotEvents := make(chan Event)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.ServeWithFilter(func(m event.Metadata) bool {
+			return m.Attributes["region"] == "us"
+		}, func(_ context.Context, e Event) error {
+			gotEvents <- e
+			return nil
+		})
+		t.Logf("subscription.ServeWithFilter error: %v", err)
+		close(servingDone)
+	}()
+
+	assertEqual(t, <-gotEvents, Event{ID: 2})
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+
+	assertEqual(t, subscription.Stats().Malformed, 0)
 }
 
-func shutdown(t *testing.T, s shutdowner) {
-	t.Helper()
+func TestSubscriptionTraceIDGenerator(t *testing.T) {
+	t.Parallel()
 
-	if err := s.Shutdown(context.Background()); err != nil {
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
 		t.Fatal(err)
 	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	publisher := event.NewPublisher[int](eventName, topic)
+
+	cases := []struct {
+		name        string
+		generator   func() string
+		wantTraceID string
+	}{
+		{name: "custom", generator: func() string { return "custom-trace-id" }, wantTraceID: "custom-trace-id"},
+		{name: "disabled", generator: func() string { return "" }, wantTraceID: ""},
+	}
+	for _, c := range cases {
+		subscription, err := event.NewSubscription[int](eventName, url, 1, event.SubscriptionWithTraceIDGenerator(c.generator))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := publisher.Publish(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		gotTraceID := make(chan string)
+		servingDone := make(chan struct{})
+		go func() {
+			err := subscription.Serve(func(ctx context.Context, _ int) error {
+				gotTraceID <- tracing.CtxGetTraceID(ctx)
+				return nil
+			})
+			t.Logf("subscription.Serve error: %v", err)
+			close(servingDone)
+		}()
+
+		if got := <-gotTraceID; got != c.wantTraceID {
+			t.Errorf("%s: got trace ID %q; want %q", c.name, got, c.wantTraceID)
+		}
+
+		shutdown(t, subscription)
+		<-servingDone
+	}
 }
 
-func newTopicURL(t *testing.T) string {
-	return "mem://" + t.Name()
+func TestSubscriptionRequestID(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[string](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type handled struct {
+		Event     string
+		RequestID string
+	}
+	got := make(chan handled)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(ctx context.Context, e string) error {
+			got <- handled{Event: e, RequestID: tracing.CtxGetRequestID(ctx)}
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	publisher := event.NewPublisher[string](eventName, topic)
+	if err := publisher.Publish(tracing.CtxWithRequestID(ctx, "request-id"), "with request ID"); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(ctx, "without request ID"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delivery order is not guaranteed
+	gotRequestIDs := map[string]string{}
+	for range 2 {
+		h := <-got
+		gotRequestIDs[h.Event] = h.RequestID
+	}
+	assertEqual(t, gotRequestIDs["with request ID"], "request-id")
+	// Events without a request ID get a new one
+	if gotRequestIDs["without request ID"] == "" {
+		t.Error("want request ID generated for event without request ID, got empty")
+	}
+
+	shutdown(t, subscription)
+	<-servingDone
 }
 
-func assertEqual[T any](t *testing.T, got T, want T) {
-	t.Helper()
-	// Parametric helps to ensure we don't compare things of different types (which doesn't make sense)
-	// so we want 2 of any that are of the same type.
-	// maybe this could be generalized in a small assert lib :-).
+func TestDecodeEnvelope(t *testing.T) {
+	t.Parallel()
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	type Event struct {
+		Field string `json:"field"`
+	}
+
+	body, err := json.Marshal(event.Envelope[Event]{
+		TraceID: "trace-id",
+		OrgID:   "org-id",
+		Name:    "test",
+		Event:   Event{Field: "data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := event.DecodeEnvelope(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, got.TraceID, "trace-id")
+	assertEqual(t, got.OrgID, "org-id")
+	assertEqual(t, got.Name, "test")
+	assertEqual(t, string(got.Event), `{"field":"data"}`)
+
+	if _, err := event.DecodeEnvelope([]byte("not json")); err == nil {
+		t.Fatal("want error decoding invalid JSON, got nil")
+	}
+}
+
+type shutdowner interface {
+	Shutdown(context.Context) error
+}
+
+func shutdown(t *testing.T, s shutdowner) {
+	t.Helper()
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTopicURL(t *testing.T) string {
+	return "mem://" + t.Name()
+}
+
+func assertEqual[T any](t *testing.T, got T, want T) {
+	t.Helper()
+	// Parametric helps to ensure we don't compare things of different types (which doesn't make sense)
+	// so we want 2 of any that are of the same type.
+	// maybe this could be generalized in a small assert lib :-).
+
+	if diff := cmp.Diff(got, want); diff != "" {
 		t.Logf("got: %v", got)
 		t.Logf("want: %v", want)
 		t.Fatalf("diff: %v", diff)
 	}
 }
+
+func TestRawSubscriptionSetMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const msgsCount = 4
+	for i := 0; i < msgsCount; i++ {
+		if err := topic.Send(ctx, &pubsub.Message{Body: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(event.Message) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+		t.Logf("rawsubscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	assertStarted := func(want int) {
+		t.Helper()
+		for i := 0; i < want; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatalf("timeout waiting for handler %d of %d to start", i+1, want)
+			}
+		}
+	}
+	assertNotStarted := func() {
+		t.Helper()
+		select {
+		case <-started:
+			t.Fatal("handler started beyond max concurrency")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	assertStarted(1)
+	assertNotStarted()
+
+	subscription.SetMaxConcurrency(3)
+	assertStarted(2)
+
+	// In flight handlers are not affected when shrinking, new ones wait until all of them are done
+	subscription.SetMaxConcurrency(1)
+	release <- struct{}{}
+	release <- struct{}{}
+	assertNotStarted()
+
+	release <- struct{}{}
+	assertStarted(1)
+	release <- struct{}{}
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestSubscriptionAcksPermanentErrors(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	for _, v := range []int{1, 2} {
+		if err := publisher.Publish(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	permanentErr := errors.New("bad data")
+	handled := make(chan int)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(_ context.Context, v int) error {
+			handled <- v
+			if v == 1 {
+				return event.Permanent(permanentErr)
+			}
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	// Events with permanent errors are not redelivered
+	got := map[int]bool{}
+	for range 2 {
+		got[<-handled] = true
+	}
+	assertEqual(t, got, map[int]bool{1: true, 2: true})
+
+	// Ack happens after the handler returns, it is async.
+	deadline := time.Now().Add(time.Second)
+	for subscription.Stats().Acked < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := subscription.Stats()
+	assertEqual(t, stats.Acked, uint64(2))
+	assertEqual(t, stats.Nacked, uint64(0))
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestSubscriptionAckOnPanic(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1, event.SubscriptionWithAckOnPanic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	for _, v := range []int{1, 2} {
+		if err := publisher.Publish(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handled := make(chan int)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(_ context.Context, v int) error {
+			handled <- v
+			if v == 1 {
+				panic("poison message")
+			}
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	// Events that panic are not redelivered
+	got := map[int]bool{}
+	for range 2 {
+		got[<-handled] = true
+	}
+	assertEqual(t, got, map[int]bool{1: true, 2: true})
+
+	// Ack happens after the handler returns, it is async.
+	deadline := time.Now().Add(time.Second)
+	for subscription.Stats().Acked < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := subscription.Stats()
+	assertEqual(t, stats.Acked, uint64(2))
+	assertEqual(t, stats.Nacked, uint64(0))
+	assertEqual(t, stats.Panics, uint64(1))
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestSubscriptionStrictDecoding(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	type Event struct {
+		ID string `json:"id"`
+	}
+	subscription, err := event.NewSubscription[Event](eventName, url, 1, event.SubscriptionWithStrictDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are published before serving, so they are received without waiting for the broker to poll.
+	unknownPublisher := event.NewPublisher[map[string]any](eventName, topic)
+	if err := unknownPublisher.Publish(ctx, map[string]any{"id": "unknown", "new_field": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := event.NewPublisher[Event](eventName, topic).Publish(ctx, Event{ID: "known"}); err != nil {
+		t.Fatal(err)
+	}
+	// Unknown envelope fields are always ignored, only the event is strictly decoded.
+	newEnvelope := `{"name":"test","new_envelope_field":"value","event":{"id":"new envelope"}}`
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte(newEnvelope)}); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan Event)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(_ context.Context, e Event) error {
+			handled <- e
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	// Delivery order is not guaranteed
+	got := map[string]bool{}
+	for range 2 {
+		got[(<-handled).ID] = true
+	}
+	assertEqual(t, got, map[string]bool{"known": true, "new envelope": true})
+
+	// Events with unknown fields are discarded as malformed (and redelivered, since they are Nacked).
+	deadline := time.Now().Add(10 * time.Second)
+	for subscription.Stats().Malformed < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if subscription.Stats().Malformed < 1 {
+		t.Fatal("want event with unknown field to be discarded as malformed")
+	}
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestSubscriptionFromRaw(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	sub, err := pubsub.OpenSubscription(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const eventName = "test"
+	subscription, err := event.NewSubscriptionFromRaw[int](eventName, event.NewRawSubscriptionFrom(sub, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	if err := event.NewPublisher[int](eventName, topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	got, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Ack()
+	assertEqual(t, got.Event, 1)
+
+	if _, err := event.NewSubscriptionFromRaw[int]("", event.NewRawSubscriptionFrom(sub, 1)); err == nil {
+		t.Fatal("want error for empty event name, got nil")
+	}
+}
+
+func TestRawSubscriptionFromInvalidConcurrency(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic for max concurrency 0, got none")
+		}
+	}()
+	event.NewRawSubscriptionFrom(nil, 0)
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("bad data")
+	permanentErr := event.Permanent(err)
+
+	if !errors.Is(permanentErr, event.ErrPermanent) {
+		t.Fatalf("got %v; want it to be event.ErrPermanent", permanentErr)
+	}
+	if !errors.Is(permanentErr, err) {
+		t.Fatalf("got %v; want it to wrap %v", permanentErr, err)
+	}
+	if event.Permanent(nil) != nil {
+		t.Fatal("want nil permanent error for nil error")
+	}
+}
+
+func TestPublishBatch(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	events := []int{}
+	want := map[int]bool{}
+	for i := 0; i < 3*event.PublishBatchMaxConcurrency; i++ {
+		events = append(events, i)
+		want[i] = true
+	}
+
+	errs := publisher.PublishBatch(ctx, events)
+	assertEqual(t, errs, make([]error, len(events)))
+
+	received, err := subscription.ReceiveN(ctx, len(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[int]bool{}
+	for _, e := range received {
+		e.Ack()
+		got[e.Event] = true
+	}
+	assertEqual(t, got, want)
+
+	// Errors are aligned with the events
+	closedTopic, err := pubsub.OpenTopic(ctx, url+"-closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, closedTopic)
+
+	errs = event.NewPublisher[int](eventName, closedTopic).PublishBatch(ctx, []int{1, 2})
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+		t.Fatalf("got errors %v; want 2 errors", errs)
+	}
+}
+
+func TestPublishBatchWithAttrs(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := pubsub.OpenSubscription(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := event.NewPublisher[int]("test", topic, event.PublisherWithBatchMaxConcurrency(1))
+	attrs := map[string]string{"key": "value"}
+	errs := publisher.PublishBatchWithAttrs(ctx, []int{1, 2, 3}, attrs)
+	assertEqual(t, event.JoinBatchErrors(errs), nil)
+
+	for range 3 {
+		msg, err := subscription.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.Ack()
+		assertEqual(t, msg.Metadata, attrs)
+	}
+}
+
+func TestJoinBatchErrors(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+
+	err := event.JoinBatchErrors([]error{nil, errA, nil, errB})
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got %v; want to wrap %v and %v", err, errA, errB)
+	}
+	assertEqual(t, err.Error(), "publishing batch: 2 of 4 events failed: event 1: a\nevent 3: b")
+	assertEqual(t, event.JoinBatchErrors([]error{nil, nil}), nil)
+	assertEqual(t, event.JoinBatchErrors(nil), nil)
+}
+
+func TestSubscriptionServingWithRaw(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type handled struct {
+		event    int
+		raw      []byte
+		metadata event.Metadata
+	}
+	received := make(chan handled)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.ServeWithRaw(func(_ context.Context, v int, raw []byte, metadata event.Metadata) error {
+			received <- handled{v, raw, metadata}
+			return nil
+		})
+		t.Logf("subscription.ServeWithRaw error: %v", err)
+		close(servingDone)
+	}()
+
+	// Extra fields are kept on the raw body
+	wantRaw := []byte(`{"trace_id":"trace","organization_id":"org","name":"test","event":666,"extra":true}`)
+	wantAttributes := map[string]string{"key": "val"}
+	if err := topic.Send(ctx, &pubsub.Message{Body: wantRaw, Metadata: wantAttributes}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	assertEqual(t, got.event, 666)
+	assertEqual(t, string(got.raw), string(wantRaw))
+	assertEqual(t, got.metadata.Attributes, wantAttributes)
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestEmptyEventNames(t *testing.T) {
+	if _, err := event.NewSubscription[int]("", newTopicURL(t), 1); err == nil {
+		t.Fatal("want error creating subscription with empty name, got nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic creating publisher with empty name")
+		}
+	}()
+	event.NewPublisher[int]("", nil)
+}
+
+func TestPublishAndGetIDInMemory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	topic, err := pubsub.OpenTopic(ctx, newTopicURL(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	// In memory brokers don't provide message IDs
+	id, err := event.NewPublisher[string]("test", topic).PublishAndGetID(ctx, "event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, id, "")
+}
+
+func TestPublishAndGetIDGoogleCloud(t *testing.T) {
+	server := googleCloudServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	topicPath := "projects/test/topics/" + googleCloudID(t)
+	if _, err := server.GServer.CreateTopic(ctx, &pubsubpb.Topic{Name: topicPath}); err != nil {
+		t.Fatal(err)
+	}
+	topic, err := pubsub.OpenTopic(ctx, "gcppubsub://"+topicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	id, err := event.NewPublisher[string]("test", topic).PublishAndGetID(ctx, "event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" {
+		t.Fatal("want message ID, got empty")
+	}
+	if server.Message(id) == nil {
+		t.Fatalf("message %q not found on server", id)
+	}
+}
@@ -0,0 +1,91 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	raw "cloud.google.com/go/pubsub/apiv1"
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gcpSubscriptionPathRE matches fully qualified Google Cloud Pub Sub subscription paths.
+var gcpSubscriptionPathRE = regexp.MustCompile("^projects/.+/subscriptions/.+$")
+
+// HealthCheck verifies that the event broker is reachable, like for readiness probes.
+// See [MessageSubscription.HealthCheck] for details.
+func (s *Subscription[T]) HealthCheck(ctx context.Context) error {
+	return s.rawsub.HealthCheck(ctx)
+}
+
+// HealthCheck verifies that the event broker is reachable and that the subscription exists, like for readiness probes.
+// It does a single lightweight request to the broker (no messages are received), so it is cheap and it doesn't affect
+// messages being served. The given context should have a deadline, since the request may hang if the broker is unreachable.
+// Subscriptions created with [NewRawSubscriptionFrom] don't know their path, so like [Publisher.HealthCheck]
+// only check that the broker answers requests.
+// For now only Google Cloud Pub Sub is checked, for other brokers (like in memory ones) it always succeeds.
+func (r *MessageSubscription) HealthCheck(ctx context.Context) error {
+	var client *raw.SubscriberClient
+	if !r.sub.As(&client) {
+		return nil
+	}
+	if r.url == "" {
+		// Any answer from the broker proves that it is reachable, even an error about the (inexistent) subscription.
+		_, err := client.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{Subscription: "projects/-/subscriptions/-"})
+		return checkBrokerAnswer(err)
+	}
+	subPath, err := gcpSubscriptionPath(r.url)
+	if err != nil {
+		return fmt.Errorf("event: health check: %w", err)
+	}
+	if _, err := client.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{Subscription: subPath}); err != nil {
+		return fmt.Errorf("event: health check: getting subscription %q: %w", subPath, err)
+	}
+	return nil
+}
+
+// HealthCheck verifies that the event broker is reachable, like for readiness probes.
+// It does a single lightweight request to the broker (nothing is published), so it is cheap.
+// The given context should have a deadline, since the request may hang if the broker is unreachable.
+// Publishers don't know the path of their topic, so the existence of the topic is not checked, only that the broker
+// answers requests (with valid credentials). For now only Google Cloud Pub Sub is checked, for other brokers
+// (like in memory ones) it always succeeds.
+func (p *Publisher[T]) HealthCheck(ctx context.Context) error {
+	var client *raw.PublisherClient
+	if !p.topic.As(&client) {
+		return nil
+	}
+	// Any answer from the broker proves that it is reachable, even an error about the (inexistent) topic.
+	_, err := client.GetTopic(ctx, &pubsubpb.GetTopicRequest{Topic: "projects/-/topics/-"})
+	return checkBrokerAnswer(err)
+}
+
+// checkBrokerAnswer checks the error of a request to the broker, only errors that indicate that the broker
+// didn't answer the request (or rejected the credentials) fail.
+func checkBrokerAnswer(err error) error {
+	switch status.Code(err) {
+	case codes.OK, codes.NotFound, codes.InvalidArgument, codes.PermissionDenied:
+		return nil
+	default:
+		return fmt.Errorf("event: health check: broker unreachable: %w", err)
+	}
+}
+
+// gcpSubscriptionPath returns the Google Cloud Pub Sub subscription path of the given subscription URL,
+// supporting the same formats as gocloud: "gcppubsub://projects/myproject/subscriptions/mysub" and "gcppubsub://myproject/mysub".
+func gcpSubscriptionPath(subURL string) (string, error) {
+	u, err := url.Parse(subURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing subscription URL %q: %w", subURL, err)
+	}
+	subPath := path.Join(u.Host, u.Path)
+	if gcpSubscriptionPathRE.MatchString(subPath) {
+		return subPath, nil
+	}
+	return fmt.Sprintf("projects/%s/subscriptions/%s", u.Host, strings.TrimPrefix(u.Path, "/")), nil
+}
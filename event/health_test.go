@@ -0,0 +1,149 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+	_ "gocloud.dev/pubsub/gcppubsub"
+)
+
+func TestHealthCheckGoogleCloud(t *testing.T) {
+	server := googleCloudServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id := googleCloudID(t)
+	topicPath := "projects/test/topics/" + id
+	subPath := "projects/test/subscriptions/" + id
+	if _, err := server.GServer.CreateTopic(ctx, &pubsubpb.Topic{Name: topicPath}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.GServer.CreateSubscription(ctx, &pubsubpb.Subscription{Name: subPath, Topic: topicPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsub.OpenTopic(ctx, "gcppubsub://"+topicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	publisher := event.NewPublisher[string]("test", topic)
+	if err := publisher.HealthCheck(ctx); err != nil {
+		t.Fatalf("publisher health check failed: %v", err)
+	}
+
+	for _, url := range []string{"gcppubsub://" + subPath, "gcppubsub://test/" + id} {
+		subscription, err := event.NewSubscription[string]("test", url, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := subscription.HealthCheck(ctx); err != nil {
+			t.Errorf("subscription %q health check failed: %v", url, err)
+		}
+		shutdown(t, subscription)
+	}
+
+	// Without the subscription URL only the broker is checked
+	sub, err := pubsub.OpenSubscription(ctx, "gcppubsub://"+subPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSub := event.NewRawSubscriptionFrom(sub, 1)
+	if err := fromSub.HealthCheck(ctx); err != nil {
+		t.Errorf("subscription created from gocloud subscription health check failed: %v", err)
+	}
+	shutdown(t, fromSub)
+
+	missing, err := event.NewSubscription[string]("test", "gcppubsub://test/missing", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, missing)
+
+	if err := missing.HealthCheck(ctx); err == nil {
+		t.Error("want error on health check of missing subscription, got nil")
+	}
+
+	// Health checks must not consume messages
+	if err := publisher.Publish(ctx, "event"); err != nil {
+		t.Fatal(err)
+	}
+	subscription, err := event.NewSubscription[string]("test", "gcppubsub://"+subPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	if err := subscription.HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Ack()
+	assertEqual(t, got.Event, "event")
+
+}
+
+func TestHealthCheckInMemory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string]("test", url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	if err := event.NewPublisher[string]("test", topic).HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := subscription.HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var (
+	googleCloudServerOnce sync.Once
+	googleCloudFakeServer *pstest.Server
+	googleCloudIDs        atomic.Int64
+)
+
+// googleCloudServer returns a fake Google Cloud Pub Sub server.
+// gocloud opens Google Cloud Pub Sub URLs with the emulator when PUBSUB_EMULATOR_HOST is set.
+// The URL opener is initialized only once, so all Google Cloud tests must share the same fake server
+// (which lives until the tests end). Tests sharing it must use different topics and subscriptions.
+func googleCloudServer() *pstest.Server {
+	googleCloudServerOnce.Do(func() {
+		googleCloudFakeServer = pstest.NewServer()
+		if err := os.Setenv("PUBSUB_EMULATOR_HOST", googleCloudFakeServer.Addr); err != nil {
+			panic(err)
+		}
+	})
+	return googleCloudFakeServer
+}
+
+// googleCloudID returns an unique ID for topics and subscriptions of the given test on the fake Google Cloud server.
+// The test name is not enough, since the server is shared by tests that run multiple times (like with -count).
+func googleCloudID(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), googleCloudIDs.Add(1))
+}
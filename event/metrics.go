@@ -9,8 +9,9 @@ import (
 // MustRegisterMetrics will register all event related metrics on the given registry.
 // If metrics with the same name already exist no the register this function will panic.
 func MustRegisterMetrics(registry *prometheus.Registry) {
-	registry.MustRegister(publishMsgBodySize, publishDuration, publishCounter,
-		processMsgBodySize, processCounter, processDuration)
+	registry.MustRegister(publishMsgBodySize, publishUncompressedBodySize, publishDuration, publishCounter,
+		processMsgBodySize, processUncompressedBodySize, processCounter, processDuration, processSkippedCounter, serveSlotWait,
+		multiPublishDuration, multiPublishCounter)
 }
 
 // SampledMessageHandler will instrument the given MessageHandler returning a new one
@@ -26,7 +27,7 @@ func SampledMessageHandler(eventName string, handler MessageHandler) MessageHand
 	}
 }
 
-func samplePublish(name string, elapsed time.Duration, bodySize int, err error) {
+func samplePublish(name string, elapsed time.Duration, bodySize, uncompressedBodySize int, err error) {
 	status := "ok"
 	if err != nil {
 		status = "error"
@@ -36,6 +37,7 @@ func samplePublish(name string, elapsed time.Duration, bodySize int, err error)
 		"name":   name,
 	}
 	publishMsgBodySize.With(labels).Observe(float64(bodySize))
+	publishUncompressedBodySize.With(labels).Observe(float64(uncompressedBodySize))
 	publishDuration.With(labels).Observe(elapsed.Seconds())
 	publishCounter.With(labels).Inc()
 }
@@ -50,11 +52,37 @@ func sampleProcess(msg Message, name string, elapsed time.Duration, err error) {
 		"name":   name,
 	}
 	processMsgBodySize.With(labels).Observe(float64(len(msg.Body)))
+	processUncompressedBodySize.With(labels).Observe(float64(uncompressedSize(msg)))
 	processDuration.With(labels).Observe(elapsed.Seconds())
 	processCounter.With(labels).Inc()
 }
 
+func sampleMultiPublish(name, target string, elapsed time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	labels := prometheus.Labels{
+		"status": status,
+		"name":   name,
+		"target": target,
+	}
+	multiPublishDuration.With(labels).Observe(elapsed.Seconds())
+	multiPublishCounter.With(labels).Inc()
+}
+
+func sampleSkipped(name string) {
+	processSkippedCounter.With(prometheus.Labels{"name": name}).Inc()
+}
+
+func sampleSlotWait(name string, elapsed time.Duration) {
+	serveSlotWait.With(prometheus.Labels{"name": name}).Observe(elapsed.Seconds())
+}
+
 var (
+	// Compressed events can be much bigger than the max message size when uncompressed
+	uncompressedBodySizeBuckets = prometheus.ExponentialBucketsRange(256, 1024*1024*100, 35)
+
 	// GCP max message size is 10mb
 	bodySizeBuckets    = prometheus.ExponentialBucketsRange(256, 1024*1024*10, 30)
 	publishMsgBodySize = prometheus.NewHistogramVec(
@@ -65,6 +93,14 @@ var (
 		},
 		[]string{"status", "name"},
 	)
+	publishUncompressedBodySize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "event_publish_msg_uncompressed_body_size_bytes",
+			Help:    "Size in bytes of published event message body before compression",
+			Buckets: uncompressedBodySizeBuckets,
+		},
+		[]string{"status", "name"},
+	)
 	publishDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "event_publish_duration_seconds",
@@ -84,6 +120,24 @@ var (
 		},
 		[]string{"status", "name"},
 	)
+	multiPublishDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "event_multi_publish_duration_seconds",
+			Help: "Duration of event publish on each target of a multi publisher",
+			Buckets: []float64{
+				.1, .2, .3, .4, .5, .6, .7, .8, .9, 1,
+				2, 3, 4, 5, 10, 15, 20, 30,
+			},
+		},
+		[]string{"status", "name", "target"},
+	)
+	multiPublishCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_multi_publish_total",
+			Help: "Total of published events on each target of a multi publisher",
+		},
+		[]string{"status", "name", "target"},
+	)
 	processDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "event_process_duration_seconds",
@@ -105,6 +159,14 @@ var (
 		},
 		[]string{"status", "name"},
 	)
+	processUncompressedBodySize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "event_process_msg_uncompressed_body_size_bytes",
+			Help:    "Size in bytes of processed event message body after decompression",
+			Buckets: uncompressedBodySizeBuckets,
+		},
+		[]string{"status", "name"},
+	)
 	processCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "event_process_total",
@@ -112,4 +174,20 @@ var (
 		},
 		[]string{"status", "name"},
 	)
+	processSkippedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_process_skipped_total",
+			Help: "Total of events skipped by a subscription filter",
+		},
+		[]string{"name"},
+	)
+	serveSlotWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "event_serve_slot_wait_seconds",
+			Help: "Duration that serving waits for a concurrency slot before receiving the next event",
+			// From 1ms up to ~8min (processing can take up to 10 minutes on GCP)
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 20),
+		},
+		[]string{"name"},
+	)
 )
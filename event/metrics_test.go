@@ -1,10 +1,20 @@
 package event_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/birdie-ai/golibs/event"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gocloud.dev/pubsub"
 )
 
 func TestRegisterMetrics(*testing.T) {
@@ -12,3 +22,214 @@ func TestRegisterMetrics(*testing.T) {
 	registry := prometheus.NewRegistry()
 	event.MustRegisterMetrics(registry)
 }
+
+func TestCompressedBodySizeMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	event.MustRegisterMetrics(registry)
+
+	ctx := context.Background()
+	// Metrics are global, the event name must be unique among tests.
+	const eventName = "compressed-body-size-metrics"
+
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bigEvent := strings.Repeat("a", 1000)
+	publisher := event.NewPublisher[string](eventName, topic, event.PublisherWithBodyCompression(100))
+	if err := publisher.Publish(ctx, bigEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan string)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(_ context.Context, e string) error {
+			handled <- e
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+	assertEqual(t, <-handled, bigEvent)
+
+	// Shutdown waits for the handler to finish, including sampling its metrics.
+	shutdown(t, subscription)
+	<-servingDone
+
+	envelope, err := json.Marshal(event.Envelope[string]{Name: eventName, Event: bigEvent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUncompressedSize := float64(len(envelope))
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishSize := histogramSum(t, metrics, "event_publish_msg_body_size_bytes", eventName)
+	processSize := histogramSum(t, metrics, "event_process_msg_body_size_bytes", eventName)
+	if publishSize >= wantUncompressedSize {
+		t.Errorf("got published body size %v; want compressed size (smaller than %v)", publishSize, wantUncompressedSize)
+	}
+	assertEqual(t, processSize, publishSize)
+	assertEqual(t, histogramSum(t, metrics, "event_publish_msg_uncompressed_body_size_bytes", eventName), wantUncompressedSize)
+	assertEqual(t, histogramSum(t, metrics, "event_process_msg_uncompressed_body_size_bytes", eventName), wantUncompressedSize)
+}
+
+func TestUncompressedBodySizeMetricIgnoresInvalidGzipTrailer(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	event.MustRegisterMetrics(registry)
+
+	ctx := context.Background()
+	// Metrics are global, the event name must be unique among tests.
+	const eventName = "invalid-gzip-trailer-metrics"
+
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	w := gzip.NewWriter(&body)
+	if _, err := w.Write([]byte(`{"name":"invalid-gzip-trailer-metrics","event":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The trailer claims a body of 4GB, impossible for such a small compressed body
+	invalidBody := body.Bytes()
+	binary.LittleEndian.PutUint32(invalidBody[len(invalidBody)-4:], math.MaxUint32)
+
+	err = topic.Send(ctx, &pubsub.Message{
+		Body:     invalidBody,
+		Metadata: map[string]string{event.ContentEncodingAttr: event.ContentEncodingGzip},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(event.SampledMessageHandler(eventName, func(event.Message) error {
+			close(handled)
+			return nil
+		}))
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+	<-handled
+
+	// Shutdown waits for the handler to finish, including sampling its metrics.
+	shutdown(t, subscription)
+	<-servingDone
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, histogramSum(t, metrics, "event_process_msg_uncompressed_body_size_bytes", eventName), float64(len(invalidBody)))
+}
+
+func TestRouterSamplesDiscardedEventsMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	event.MustRegisterMetrics(registry)
+
+	ctx := context.Background()
+	// Metrics are global, the event names must be unique among tests.
+	const (
+		malformedName = "router-malformed-metrics"
+		unknownName   = "router-unknown-metrics"
+	)
+
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	rawsub, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := event.NewRouter(rawsub)
+	event.RegisterHandler(router, malformedName, func(context.Context, int) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	if err := event.NewPublisher[string](malformedName, topic).Publish(ctx, "not a number"); err != nil {
+		t.Fatal(err)
+	}
+	if err := event.NewPublisher[int](unknownName, topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	servingDone := make(chan struct{})
+	go func() {
+		err := router.Serve()
+		t.Logf("router.Serve error: %v", err)
+		close(servingDone)
+	}()
+	for rawsub.Stats().Malformed < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Shutdown waits for the handler to finish, including sampling its metrics.
+	shutdown(t, router)
+	<-servingDone
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{malformedName, unknownName} {
+		if size := histogramSum(t, metrics, "event_process_msg_body_size_bytes", name); size == 0 {
+			t.Errorf("got processed body size 0 for %q; want the size of the discarded events", name)
+		}
+	}
+}
+
+// histogramSum returns the sum of the samples of the histogram with the given metric name and event name label.
+func histogramSum(t *testing.T, metrics []*dto.MetricFamily, metricName, eventName string) float64 {
+	t.Helper()
+
+	for _, family := range metrics {
+		if family.GetName() != metricName {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == eventName {
+					return metric.GetHistogram().GetSampleSum()
+				}
+			}
+		}
+	}
+	t.Fatalf("metric %q with name %q not found", metricName, eventName)
+	return 0
+}
@@ -0,0 +1,79 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+// Middleware wraps a [Handler] returning a new [Handler], like HTTP middlewares.
+// It is useful for cross-cutting concerns like logging, timeouts, auth checks, etc.
+type Middleware[T any] func(Handler[T]) Handler[T]
+
+// Chain wraps the given handler with all the given middlewares.
+// Middlewares are applied in the order they are given, the first middleware is the outermost one.
+// So Chain(h, m1, m2) is the same as m1(m2(h)): when an event is handled m1 is called first,
+// then m2 and then h.
+func Chain[T any](h Handler[T], middlewares ...Middleware[T]) Handler[T] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// RecoverMiddleware creates a [Middleware] that recovers from panics on the handler.
+// The panic is logged with its stack trace and an error is returned instead.
+func RecoverMiddleware[T any]() Middleware[T] {
+	return func(h Handler[T]) Handler[T] {
+		return func(ctx context.Context, event T) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					// 64KB, if it is good enough for Go's standard lib it is good enough for us :-)
+					const size = 64 << 10
+					buf := make([]byte, size)
+					buf = buf[:runtime.Stack(buf, false)]
+					slog.FromCtx(ctx).Error("panic: event handler",
+						"error", r,
+						"stack_trace", string(buf))
+					err = fmt.Errorf("panic handling event: %v", r)
+				}
+			}()
+			return h(ctx, event)
+		}
+	}
+}
+
+// TimeoutMiddleware creates a [Middleware] that limits how long the handler can take by
+// passing a context with the given timeout to it. The handler must respect the context for this to work.
+func TimeoutMiddleware[T any](timeout time.Duration) Middleware[T] {
+	return func(h Handler[T]) Handler[T] {
+		return func(ctx context.Context, event T) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return h(ctx, event)
+		}
+	}
+}
+
+// LogMiddleware creates a [Middleware] that logs each handled event on the DEBUG level,
+// including how long it took to handle it. Failures are logged on the ERROR level.
+func LogMiddleware[T any]() Middleware[T] {
+	return func(h Handler[T]) Handler[T] {
+		return func(ctx context.Context, event T) error {
+			start := time.Now()
+			err := h(ctx, event)
+			elapsed := time.Since(start)
+
+			log := slog.FromCtx(ctx).With("elapsed", elapsed.String())
+			if err != nil {
+				log.Error("event: handling event failed", "error", err)
+				return err
+			}
+			log.Debug("event: handled event")
+			return nil
+		}
+	}
+}
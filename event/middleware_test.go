@@ -0,0 +1,195 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"github.com/birdie-ai/golibs/tracing"
+	"gocloud.dev/pubsub"
+)
+
+func TestChainOrder(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	middleware := func(name string) event.Middleware[int] {
+		return func(h event.Handler[int]) event.Handler[int] {
+			return func(ctx context.Context, e int) error {
+				calls = append(calls, name)
+				return h(ctx, e)
+			}
+		}
+	}
+	handler := event.Chain(func(context.Context, int) error {
+		calls = append(calls, "handler")
+		return nil
+	}, middleware("first"), middleware("second"), middleware("third"))
+
+	if err := handler(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, calls, []string{"first", "second", "third", "handler"})
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	t.Parallel()
+
+	handler := event.Chain(func(context.Context, int) error {
+		panic("oh no")
+	}, event.RecoverMiddleware[int]())
+
+	if err := handler(context.Background(), 1); err == nil {
+		t.Fatal("want error after panic, got nil")
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	handler := event.Chain(func(ctx context.Context, _ int) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, event.TimeoutMiddleware[int](time.Millisecond))
+
+	if err := handler(context.Background(), 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLogMiddleware(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("handler error")
+	handler := event.Chain(func(context.Context, int) error {
+		return wantErr
+	}, event.LogMiddleware[int]())
+
+	if err := handler(context.Background(), 1); !errors.Is(err, wantErr) {
+		t.Fatalf("got error %v; want %v", err, wantErr)
+	}
+}
+
+func TestSubscriptionUse(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[string]("test", url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	middleware := func(name string) event.Middleware[string] {
+		return func(h event.Handler[string]) event.Handler[string] {
+			return func(ctx context.Context, e string) error {
+				calls = append(calls, name+":"+tracing.CtxGetOrgID(ctx))
+				return h(ctx, e)
+			}
+		}
+	}
+	subscription.Use(middleware("first"), blockOrgs[string]("blocked"))
+	subscription.Use(middleware("second"))
+
+	// Events are published before serving, so they are received without waiting for the broker to poll.
+	publisher := event.NewPublisher[string]("test", topic)
+	for _, orgID := range []string{"blocked", "allowed"} {
+		if err := publisher.Publish(tracing.CtxWithOrgID(ctx, orgID), orgID+" event"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handled := make(chan string)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.ServeWithMetadata(func(_ context.Context, e string, _ event.Metadata) error {
+			handled <- e
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	// The blocked event may be redelivered, but it never reaches the handler.
+	assertEqual(t, <-handled, "allowed event")
+
+	// Delivery order is not guaranteed, so the blocked event may be rejected only after the allowed one is handled.
+	deadline := time.Now().Add(10 * time.Second)
+	for subscription.Stats().Nacked < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if subscription.Stats().Nacked < 1 {
+		t.Fatal("want blocked event to be nacked")
+	}
+
+	shutdown(t, subscription)
+	<-servingDone
+
+	// The blocked event may be received (and rejected) at any time, so only the order of the allowed event is checked.
+	var allowedCalls, blockedCalls []string
+	for _, call := range calls {
+		switch call {
+		case "second:blocked":
+			t.Fatal("blocked event passed the blocklist middleware")
+		case "first:allowed", "second:allowed":
+			allowedCalls = append(allowedCalls, call)
+		case "first:blocked":
+			blockedCalls = append(blockedCalls, call)
+		}
+	}
+	if len(blockedCalls) == 0 {
+		t.Fatal("blocked event never reached the first middleware")
+	}
+	assertEqual(t, allowedCalls, []string{"first:allowed", "second:allowed"})
+}
+
+// blockOrgs rejects events from the given organizations, returning an error (so they are Nacked).
+func blockOrgs[T any](orgIDs ...string) event.Middleware[T] {
+	blocked := map[string]bool{}
+	for _, orgID := range orgIDs {
+		blocked[orgID] = true
+	}
+	return func(h event.Handler[T]) event.Handler[T] {
+		return func(ctx context.Context, e T) error {
+			if orgID := tracing.CtxGetOrgID(ctx); blocked[orgID] {
+				return fmt.Errorf("organization %q is blocked", orgID)
+			}
+			return h(ctx, e)
+		}
+	}
+}
+
+func ExampleSubscription_Use() {
+	subscription, err := event.NewSubscription[string]("event-name", "mem://topic", 10)
+	if err != nil {
+		panic(err)
+	}
+	// Events from blocked organizations are rejected with an error, so they are Nacked.
+	blocked := map[string]bool{"blocked-org": true}
+	subscription.Use(func(h event.Handler[string]) event.Handler[string] {
+		return func(ctx context.Context, e string) error {
+			if orgID := tracing.CtxGetOrgID(ctx); blocked[orgID] {
+				return fmt.Errorf("organization %q is blocked", orgID)
+			}
+			return h(ctx, e)
+		}
+	}, event.LogMiddleware[string]())
+
+	go func() {
+		_ = subscription.Serve(func(ctx context.Context, e string) error {
+			fmt.Println(e)
+			return nil
+		})
+	}()
+}
@@ -0,0 +1,81 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/birdie-ai/golibs/slog"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// MultiPublisher publishes the same event on multiple publishers (fan-out), like when dual-writing to multiple
+// topics during migrations. Each underlying [Publisher] publishes (and samples its metrics) independently.
+type MultiPublisher[T any] struct {
+	targets []MultiTarget[T]
+}
+
+// MultiTarget is one of the publishers of a [MultiPublisher].
+type MultiTarget[T any] struct {
+	// Name identifies the target (like the topic name) on logs and on the `target` label of the
+	// `event_multi_publish_*` metrics, since all publishers usually have the same event name.
+	Name      string
+	Publisher *Publisher[T]
+}
+
+// NewMultiPublisher creates a new [MultiPublisher] that publishes events on all the given targets.
+func NewMultiPublisher[T any](targets ...MultiTarget[T]) *MultiPublisher[T] {
+	return &MultiPublisher[T]{targets: targets}
+}
+
+// Publish will publish the given event on all publishers concurrently.
+// It returns an error aggregating the errors of all publishers that failed.
+func (m *MultiPublisher[T]) Publish(ctx context.Context, event T) error {
+	return m.PublishWithAttrs(ctx, event, nil)
+}
+
+// PublishWithAttrs will publish the given event with the provided attributes on all publishers concurrently.
+// It returns an error aggregating the errors of all publishers that failed.
+func (m *MultiPublisher[T]) PublishWithAttrs(ctx context.Context, event T, attributes map[string]string) error {
+	return errors.Join(m.publish(ctx, event, attributes)...)
+}
+
+// PublishBestEffort will publish the given event on all publishers concurrently tolerating partial failures.
+// Failures are logged and an error is returned only if all publishers failed.
+func (m *MultiPublisher[T]) PublishBestEffort(ctx context.Context, event T) error {
+	errs := m.publish(ctx, event, nil)
+
+	var failed int
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		slog.FromCtx(ctx).Warn("event: multi publisher: best effort publish failed",
+			"target", m.targets[i].Name, "name", m.targets[i].Publisher.Name(), "error", err)
+	}
+	if failed > 0 && failed == len(errs) {
+		return fmt.Errorf("all %d publishers failed: %w", failed, errors.Join(errs...))
+	}
+	return nil
+}
+
+// publish publishes on all targets returning the errors in the same order as the targets.
+func (m *MultiPublisher[T]) publish(ctx context.Context, event T, attributes map[string]string) []error {
+	errs := make([]error, len(m.targets))
+	p := pool.New()
+
+	for i, v := range m.targets {
+		target := v
+
+		p.Go(func() {
+			start := time.Now()
+			errs[i] = target.Publisher.PublishWithAttrs(ctx, event, attributes)
+			sampleMultiPublish(target.Publisher.Name(), target.Name, time.Since(start), errs[i])
+		})
+	}
+	p.Wait()
+
+	return errs
+}
@@ -0,0 +1,160 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/birdie-ai/golibs/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/pubsub"
+)
+
+func TestMultiPublisher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+
+	var (
+		targets       []event.MultiTarget[int]
+		subscriptions []*event.Subscription[int]
+	)
+	for _, suffix := range []string{"-a", "-b"} {
+		url := newTopicURL(t) + suffix
+		topic, err := pubsub.OpenTopic(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shutdown(t, topic)
+
+		subscription, err := event.NewSubscription[int](eventName, url, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shutdown(t, subscription)
+
+		targets = append(targets, event.MultiTarget[int]{Name: url, Publisher: event.NewPublisher[int](eventName, topic)})
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	multi := event.NewMultiPublisher(targets...)
+	if err := multi.Publish(ctx, 666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subscription := range subscriptions {
+		got, err := subscription.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got.Ack()
+		assertEqual(t, got.Event, 666)
+	}
+}
+
+func TestMultiPublisherPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const eventName = "test"
+
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	closedTopic, err := pubsub.OpenTopic(ctx, url+"-closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, closedTopic)
+
+	okTarget := event.MultiTarget[int]{Name: "ok", Publisher: event.NewPublisher[int](eventName, topic)}
+	failTarget := event.MultiTarget[int]{Name: "fail", Publisher: event.NewPublisher[int](eventName, closedTopic)}
+
+	multi := event.NewMultiPublisher(okTarget, failTarget)
+	if err := multi.Publish(ctx, 1); err == nil {
+		t.Fatal("want error on partial failure, got nil")
+	}
+	if err := multi.PublishBestEffort(ctx, 2); err != nil {
+		t.Fatalf("want no error on best effort partial failure, got %v", err)
+	}
+
+	onlyFailures := event.NewMultiPublisher(failTarget, failTarget)
+	if err := onlyFailures.PublishBestEffort(ctx, 3); err == nil {
+		t.Fatal("want error when all publishers fail, got nil")
+	}
+
+	// Delivery order is not guaranteed
+	got := map[int]bool{}
+	for range 2 {
+		msg, err := subscription.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.Ack()
+		got[msg.Event] = true
+	}
+	assertEqual(t, got, map[int]bool{1: true, 2: true})
+}
+
+func TestMultiPublisherMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	event.MustRegisterMetrics(registry)
+
+	ctx := context.Background()
+	// Metrics are global, the event name must be unique among tests.
+	const eventName = "multi-publisher-metrics"
+
+	url := newTopicURL(t)
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	closedTopic, err := pubsub.OpenTopic(ctx, url+"-closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, closedTopic)
+
+	multi := event.NewMultiPublisher(
+		event.MultiTarget[int]{Name: "ok-topic", Publisher: event.NewPublisher[int](eventName, topic)},
+		event.MultiTarget[int]{Name: "closed-topic", Publisher: event.NewPublisher[int](eventName, closedTopic)},
+	)
+	for i := range 2 {
+		_ = multi.Publish(ctx, i)
+	}
+
+	metrics, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, family := range metrics {
+		if family.GetName() != "event_multi_publish_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["name"] == eventName {
+				got[labels["target"]+":"+labels["status"]] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	assertEqual(t, got, map[string]float64{"ok-topic:ok": 2, "closed-topic:error": 2})
+}
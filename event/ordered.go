@@ -0,0 +1,109 @@
+package event
+
+import "sync"
+
+// OrderingKeyAttr is the message attribute with the ordering key of a message, see [MessageSubscription.ServeOrdered].
+// Publishers set it with [Publisher.PublishWithAttrs].
+const OrderingKeyAttr = "ordering_key"
+
+// ServeOrdered will start serving all events from the subscription like [Subscription.Serve], but events with the
+// same ordering key are handled one at a time, in the order they are received.
+// See [MessageSubscription.ServeOrdered] for details.
+func (s *Subscription[T]) ServeOrdered(handler Handler[T]) error {
+	return s.rawsub.ServeOrdered(SampledMessageHandler(s.name, func(msg Message) error {
+		ctx, event, err := s.createEvent(&msg)
+		if err != nil {
+			return err
+		}
+		return s.handle(ctx, event.Event, handler)
+	}))
+}
+
+// ServeOrdered will start serving all messages from the subscription like [MessageSubscription.Serve], but messages with
+// the same ordering key (the [OrderingKeyAttr] attribute) are handled one at a time, in the order they are received.
+// Messages with different ordering keys (or without one) are handled concurrently.
+// Ordering is done in process, so it works with any broker (like in memory ones), but the order is only guaranteed
+// if the broker delivers the messages in order and to a single subscriber.
+//
+// All received messages count for the max concurrency, including the ones waiting for a message with the same
+// ordering key to be handled, so the max concurrency also limits how many messages are waiting.
+// When a message is Nacked, the messages with the same ordering key that are waiting are also Nacked
+// so they are redelivered after it (if the broker keeps the order of redeliveries).
+func (r *MessageSubscription) ServeOrdered(handler MessageHandler) error {
+	queues := &keyQueues{queues: map[string][]*message{}}
+	for {
+		rmsg, err := r.receiveNext()
+		if err != nil {
+			return err
+		}
+		key := rmsg.Metadata.Attributes[OrderingKeyAttr]
+		if key != "" && queues.push(key, rmsg) {
+			// The key is already being handled, the message will be handled after the ones received before it.
+			continue
+		}
+		go func() {
+			for rmsg != nil {
+				acked := r.handle(rmsg, handler)
+				r.concurrency.release()
+				r.handlers.done()
+				if key == "" {
+					return
+				}
+				if !acked {
+					for _, waiting := range queues.clear(key) {
+						waiting.Nack()
+						r.concurrency.release()
+						r.handlers.done()
+					}
+				}
+				rmsg = queues.next(key)
+			}
+		}()
+	}
+}
+
+// keyQueues has the messages waiting to be handled for each ordering key that is being handled.
+type keyQueues struct {
+	mu     sync.Mutex
+	queues map[string][]*message
+}
+
+// push adds the message to the queue of the given key if the key is being handled, returning true.
+// If the key is not being handled it is marked as being handled and false is returned, the caller must handle the message.
+func (q *keyQueues) push(key string, msg *message) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, ok := q.queues[key]
+	if !ok {
+		q.queues[key] = nil
+		return false
+	}
+	q.queues[key] = append(queue, msg)
+	return true
+}
+
+// next returns the next message to be handled for the given key.
+// If there are no messages waiting it returns nil and the key is no longer being handled.
+func (q *keyQueues) next(key string) *message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queues[key]
+	if len(queue) == 0 {
+		delete(q.queues, key)
+		return nil
+	}
+	q.queues[key] = queue[1:]
+	return queue[0]
+}
+
+// clear removes all messages waiting for the given key, returning them.
+func (q *keyQueues) clear(key string) []*message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queues[key]
+	q.queues[key] = nil
+	return queue
+}
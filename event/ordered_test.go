@@ -0,0 +1,175 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestSubscriptionServeOrdered(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const (
+		eventName = "test"
+		keyEvents = 2
+	)
+	subscription, err := event.NewSubscription[int](eventName, url, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	want := map[string][]int{}
+	// Each round publishes one event of each key, events of key "a" are even and events of key "b" are odd.
+	publishRound := func(round int) {
+		for _, i := range []int{round * 2, round*2 + 1} {
+			key := keyFromEvent(i)
+			want[key] = append(want[key], i)
+			if err := publisher.PublishWithAttrs(ctx, i, map[string]string{event.OrderingKeyAttr: key}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// The first round is published before serving, so it is received without waiting for the broker to poll.
+	publishRound(0)
+
+	var (
+		mu      sync.Mutex
+		got     = map[string][]int{}
+		running = map[string]bool{}
+	)
+	bHandled := make(chan struct{})
+	handled := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.ServeOrdered(func(ctx context.Context, e int) error {
+			key := keyFromEvent(e)
+			mu.Lock()
+			if running[key] {
+				t.Errorf("events with key %q handled concurrently", key)
+			}
+			running[key] = true
+			mu.Unlock()
+
+			// The first event of "a" can only finish after an event of "b" is handled, so keys are handled concurrently.
+			if e == 0 {
+				<-bHandled
+			}
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running[key] = false
+			got[key] = append(got[key], e)
+			if key == "b" && len(got[key]) == 1 {
+				close(bHandled)
+			}
+			mu.Unlock()
+			handled <- struct{}{}
+			return nil
+		})
+		t.Logf("subscription.ServeOrdered error: %v", err)
+		close(servingDone)
+	}()
+
+	for round := 1; round < keyEvents; round++ {
+		// The in memory broker doesn't deliver messages in order, so the events of a round are received before
+		// publishing the next one. Waiting for each event would be slow, since the broker polls for new messages.
+		for subscription.Stats().Received < uint64(round*2) {
+			time.Sleep(time.Millisecond)
+		}
+		publishRound(round)
+	}
+
+	for range keyEvents * 2 {
+		<-handled
+	}
+
+	shutdown(t, subscription)
+	<-servingDone
+
+	assertEqual(t, got, want)
+}
+
+func TestRawSubscriptionServeOrderedNacksWaitingMessages(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sent before serving, so they are received without waiting for the broker to poll.
+	for _, body := range []string{"first", "second"} {
+		msg := &pubsub.Message{
+			Body:     []byte(body),
+			Metadata: map[string]string{event.OrderingKeyAttr: "key"},
+		}
+		if err := topic.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failed := false
+	handled := make(chan string)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.ServeOrdered(func(msg event.Message) error {
+			if !failed {
+				// Fail the first message only after the second one is waiting.
+				for subscription.Stats().Received < 2 {
+					time.Sleep(time.Millisecond)
+				}
+				failed = true
+				return errors.New("failed")
+			}
+			handled <- string(msg.Body)
+			return nil
+		})
+		t.Logf("subscription.ServeOrdered error: %v", err)
+		close(servingDone)
+	}()
+
+	// Both messages are redelivered, since the waiting message is Nacked with the failed one.
+	got := map[string]bool{}
+	for range 2 {
+		got[<-handled] = true
+	}
+	assertEqual(t, got, map[string]bool{"first": true, "second": true})
+
+	shutdown(t, subscription)
+	<-servingDone
+
+	stats := subscription.Stats()
+	assertEqual(t, stats.Nacked, uint64(2))
+	assertEqual(t, stats.Acked, uint64(2))
+}
+
+func keyFromEvent(e int) string {
+	if e%2 == 0 {
+		return "a"
+	}
+	return "b"
+}
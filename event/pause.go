@@ -0,0 +1,96 @@
+package event
+
+import "sync"
+
+// Pause stops [MessageSubscription.Serve] from receiving new messages until [MessageSubscription.Resume] is called,
+// without shutting down the subscription. Messages already received (being handled) are not affected and are
+// Acked/Nacked as usual. A receive that is already in progress may still deliver one message after Pause returns.
+// Messages that the underlying pubsub client may have buffered are redelivered by the event broker
+// if they are not handled before their ack deadline. Calling Pause on a paused subscription does nothing.
+func (r *MessageSubscription) Pause() {
+	r.pause.close()
+}
+
+// Resume restarts receiving messages on a subscription paused with [MessageSubscription.Pause].
+// Calling Resume on a subscription that is not paused does nothing.
+func (r *MessageSubscription) Resume() {
+	r.pause.open()
+}
+
+// Paused returns true if the subscription is paused, see [MessageSubscription.Pause].
+func (r *MessageSubscription) Paused() bool {
+	return r.pause.closed()
+}
+
+// Pause stops [Subscription.Serve] from receiving new events until [Subscription.Resume] is called.
+// See [MessageSubscription.Pause] for details.
+func (s *Subscription[T]) Pause() {
+	s.rawsub.Pause()
+}
+
+// Resume restarts receiving events on a subscription paused with [Subscription.Pause].
+func (s *Subscription[T]) Resume() {
+	s.rawsub.Resume()
+}
+
+// Paused returns true if the subscription is paused, see [Subscription.Pause].
+func (s *Subscription[T]) Paused() bool {
+	return s.rawsub.Paused()
+}
+
+// gate blocks callers of wait while it is closed, until it is opened or stopped.
+// A stopped gate never blocks again.
+type gate struct {
+	mu       sync.Mutex
+	opened   chan struct{} // nil when the gate is open
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newGate() *gate {
+	return &gate{stopped: make(chan struct{})}
+}
+
+func (g *gate) wait() {
+	g.mu.Lock()
+	opened := g.opened
+	g.mu.Unlock()
+
+	if opened == nil {
+		return
+	}
+	select {
+	case <-opened:
+	case <-g.stopped:
+	}
+}
+
+func (g *gate) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.opened == nil {
+		g.opened = make(chan struct{})
+	}
+}
+
+func (g *gate) open() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.opened != nil {
+		close(g.opened)
+		g.opened = nil
+	}
+}
+
+func (g *gate) closed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.opened != nil
+}
+
+func (g *gate) stop() {
+	g.stopOnce.Do(func() { close(g.stopped) })
+}
@@ -0,0 +1,110 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestSubscriptionPauseResume(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription.Pause()
+	subscription.Pause()
+	assertEqual(t, subscription.Paused(), true)
+
+	// Published before serving, so it would be received right away if serving was not paused.
+	publisher := event.NewPublisher[int](eventName, topic)
+	if err := publisher.Publish(ctx, 666); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan int)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(_ context.Context, v int) error {
+			handled <- v
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	select {
+	case v := <-handled:
+		t.Fatalf("handled event %d while paused", v)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	subscription.Resume()
+	assertEqual(t, subscription.Paused(), false)
+
+	select {
+	case v := <-handled:
+		assertEqual(t, v, 666)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event after resume")
+	}
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
+
+func TestSubscriptionShutdownWhilePaused(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscription.Pause()
+
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(event.Message) error {
+			return nil
+		})
+		t.Logf("rawsubscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+
+	select {
+	case <-servingDone:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for paused serving to stop after shutdown")
+	}
+}
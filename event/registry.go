@@ -0,0 +1,80 @@
+package event
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"gocloud.dev/pubsub"
+)
+
+type (
+	// Registry declares event names and their Go types in a single place.
+	// Each event name can be registered only once, avoiding different parts of a service
+	// publishing/subscribing the same event name with different types (or the same type with different names).
+	// It is safe to use a [Registry] concurrently.
+	Registry struct {
+		mutex  sync.Mutex
+		events map[string]reflect.Type
+	}
+
+	// Registered represents an event of type [T] registered on a [Registry].
+	// It creates publishers and subscriptions for the registered event name.
+	Registered[T any] struct {
+		name string
+	}
+)
+
+// NewRegistry creates a new empty [Registry].
+func NewRegistry() *Registry {
+	return &Registry{
+		events: map[string]reflect.Type{},
+	}
+}
+
+// Register registers the event [T] with the given name on the registry.
+// It returns an error if the name is empty or was already registered.
+func Register[T any](r *Registry, name string) (*Registered[T], error) {
+	if name == "" {
+		return nil, fmt.Errorf("registering event: name can't be empty")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if t, ok := r.events[name]; ok {
+		return nil, fmt.Errorf("registering event %q: already registered with type %v", name, t)
+	}
+	r.events[name] = reflect.TypeFor[T]()
+	return &Registered[T]{name: name}, nil
+}
+
+// Names returns the names of all events registered on the registry, sorted.
+func (r *Registry) Names() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names := make([]string, 0, len(r.events))
+	for name := range r.events {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Name returns the name of the registered event.
+func (e *Registered[T]) Name() string {
+	return e.name
+}
+
+// NewPublisher creates a new [Publisher] for the registered event on the given topic.
+func (e *Registered[T]) NewPublisher(t *pubsub.Topic, options ...PublisherOption) *Publisher[T] {
+	return NewPublisher[T](e.name, t, options...)
+}
+
+// NewSubscription creates a new [Subscription] for the registered event.
+// See [NewSubscription] for details.
+func (e *Registered[T]) NewSubscription(url string, maxConcurrency int, options ...SubscriptionOption) (*Subscription[T], error) {
+	return NewSubscription[T](e.name, url, maxConcurrency, options...)
+}
@@ -0,0 +1,83 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	type (
+		EventA struct {
+			A string `json:"a"`
+		}
+		EventB struct {
+			B int `json:"b"`
+		}
+	)
+
+	registry := event.NewRegistry()
+
+	eventA, err := event.Register[EventA](registry, "event_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventB, err := event.Register[EventB](registry, "event_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := event.Register[EventB](registry, "event_a"); err == nil {
+		t.Fatal("want error registering duplicated name, got nil")
+	}
+	if _, err := event.Register[EventA](registry, "event_a"); err == nil {
+		t.Fatal("want error registering duplicated name with same type, got nil")
+	}
+	if _, err := event.Register[EventA](registry, ""); err == nil {
+		t.Fatal("want error registering empty name, got nil")
+	}
+
+	assertEqual(t, registry.Names(), []string{"event_a", "event_b"})
+	assertEqual(t, eventA.Name(), "event_a")
+	assertEqual(t, eventB.Name(), "event_b")
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := eventA.NewSubscription(url, 1, event.SubscriptionWithTraceIDGenerator(func() string {
+		return "generated"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	publisher := eventA.NewPublisher(topic)
+	assertEqual(t, publisher.Name(), eventA.Name())
+	assertEqual(t, subscription.Name(), eventA.Name())
+
+	want := EventA{A: "data"}
+	if err := publisher.Publish(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := subscription.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Ack()
+
+	assertEqual(t, got.Event, want)
+	// The event is published with no trace ID, so the configured generator is used.
+	assertEqual(t, got.TraceID, "generated")
+}
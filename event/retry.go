@@ -0,0 +1,103 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	raw "cloud.google.com/go/pubsub/apiv1"
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"github.com/birdie-ai/golibs/slog"
+)
+
+// maxRetryAfter is the max ack deadline supported by Google Cloud Pub Sub.
+const maxRetryAfter = 600 * time.Second
+
+// retryAfterTimeout is the timeout of the request that delays the redelivery of a message.
+const retryAfterTimeout = 30 * time.Second
+
+type retryAfterError struct {
+	delay time.Duration
+}
+
+// RetryAfter returns an error that handlers can return (possibly wrapped) to hint that the message should
+// be redelivered only after the given delay, instead of immediately, avoiding hot loops on messages that can't
+// be handled right now (like when a dependency is down).
+//
+// How the hint is honored depends on the broker:
+//
+//   - Google Cloud Pub Sub: the ack deadline of the message is modified to the delay (rounded down to seconds
+//     and limited to 600 seconds, the max supported), so the message is redelivered after the delay.
+//     This requires the subscription URL, so subscriptions created with [NewRawSubscriptionFrom] Nack immediately.
+//   - Other brokers (like in memory ones): the message is Nacked immediately, like any other error.
+//
+// If delaying the redelivery fails the error is logged and the message is Nacked immediately.
+// The message is always counted as Nacked on the subscription stats.
+func RetryAfter(d time.Duration) error {
+	return &retryAfterError{delay: d}
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("retry after %v", e.delay)
+}
+
+// nackAfter nacks the given message delaying its redelivery (when supported by the broker).
+func (r *MessageSubscription) nackAfter(rmsg *message, delay time.Duration) {
+	var client *raw.SubscriberClient
+	var recvmsg *pubsubpb.ReceivedMessage
+	if r.url == "" || !r.sub.As(&client) || !rmsg.msg.As(&recvmsg) {
+		rmsg.Nack()
+		return
+	}
+	if err := modifyAckDeadline(client, r.url, recvmsg.AckId, delay); err != nil {
+		slog.FromCtx(messageContext(rmsg.Message)).Error("message subscription: unable to delay message redelivery, nacking it",
+			"error", err,
+			"delay", delay,
+			"metadata", rmsg.Metadata)
+		rmsg.Nack()
+		return
+	}
+	// The message must not be Nacked on gocloud, since that would redeliver it immediately.
+	r.stats.nacked.Add(1)
+}
+
+// messageContext creates a context with the tracing information of the given message, when its body is an event
+// envelope, so logs about raw messages can be correlated with the event. Otherwise the context has no tracing.
+func messageContext(msg Message) context.Context {
+	body, err := decompressBody(msg, DefaultMaxDecompressedSize)
+	if err != nil {
+		return context.Background()
+	}
+	var envelope Envelope[json.RawMessage]
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.TraceID == "" {
+		return context.Background()
+	}
+	return newEventContext(envelope.TraceID, envelope.OrgID, envelope.RequestID)
+}
+
+func modifyAckDeadline(client *raw.SubscriberClient, subURL, ackID string, delay time.Duration) error {
+	subPath, err := gcpSubscriptionPath(subURL)
+	if err != nil {
+		return err
+	}
+	delay = min(max(delay, 0), maxRetryAfter)
+	ctx, cancel := context.WithTimeout(context.Background(), retryAfterTimeout)
+	defer cancel()
+
+	return client.ModifyAckDeadline(ctx, &pubsubpb.ModifyAckDeadlineRequest{
+		Subscription:       subPath,
+		AckIds:             []string{ackID},
+		AckDeadlineSeconds: int32(delay / time.Second),
+	})
+}
+
+// retryAfterDelay returns the delay of a [RetryAfter] error wrapped on the given error.
+func retryAfterDelay(err error) (time.Duration, bool) {
+	var retryErr *retryAfterError
+	if !errors.As(err, &retryErr) {
+		return 0, false
+	}
+	return retryErr.delay, true
+}
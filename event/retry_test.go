@@ -0,0 +1,135 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestRetryAfterGoogleCloud(t *testing.T) {
+	server := googleCloudServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id := googleCloudID(t)
+	topicPath := "projects/test/topics/" + id
+	subPath := "projects/test/subscriptions/" + id
+	if _, err := server.GServer.CreateTopic(ctx, &pubsubpb.Topic{Name: topicPath}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.GServer.CreateSubscription(ctx, &pubsubpb.Subscription{Name: subPath, Topic: topicPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := pubsub.OpenTopic(ctx, "gcppubsub://"+topicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription("gcppubsub://"+subPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Published before serving, so it is received without waiting for the broker to poll.
+	if err := event.NewPublisher[string]("test", topic).Publish(ctx, "retry"); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan event.Message)
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(msg event.Message) error {
+			handled <- msg
+			return fmt.Errorf("wrapped: %w", event.RetryAfter(time.Hour))
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	msg := <-handled
+	deadline := time.Now().Add(time.Second)
+	for subscription.Stats().Nacked < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-servingDone
+
+	stats := subscription.Stats()
+	assertEqual(t, stats.Nacked, uint64(1))
+	assertEqual(t, stats.Acked, uint64(0))
+
+	// The delay is limited by the max ack deadline supported by Google Cloud
+	pstestMsg := server.Message(msg.Metadata.ID)
+	if pstestMsg == nil {
+		t.Fatalf("message %q not found on server", msg.Metadata.ID)
+	}
+	for _, modack := range pstestMsg.Modacks {
+		if modack.AckDeadline == 600 {
+			return
+		}
+	}
+	t.Fatalf("want message ack deadline modified to 600s, got modacks: %v", pstestMsg.Modacks)
+}
+
+func TestRetryAfterFallbackToNack(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Published before serving, so it is received without waiting for the broker to poll.
+	if err := event.NewPublisher[string]("test", topic).Publish(ctx, "retry"); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan struct{})
+	servingDone := make(chan struct{})
+	deliveries := 0
+	go func() {
+		err := subscription.Serve(func(event.Message) error {
+			deliveries++
+			if deliveries == 1 {
+				return event.RetryAfter(time.Hour)
+			}
+			close(handled)
+			return nil
+		})
+		t.Logf("subscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	// In memory brokers don't support delays, so the message is Nacked and redelivered immediately
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for message redelivery")
+	}
+
+	shutdown(t, subscription)
+	<-servingDone
+
+	stats := subscription.Stats()
+	assertEqual(t, stats.Nacked, uint64(1))
+	assertEqual(t, stats.Acked, uint64(1))
+}
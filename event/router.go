@@ -0,0 +1,137 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/birdie-ai/golibs/slog"
+	"github.com/google/uuid"
+)
+
+// Router serves events with different names from a single subscription, dispatching each event to the
+// handler registered for its name (see [RegisterHandler]). It is useful for topics that have multiple types of events,
+// avoiding one subscription per event type where each subscription would discard the events of other types.
+type Router struct {
+	rawsub   *MessageSubscription
+	opts     subscriptionOptions
+	routes   map[string]route
+	fallback MessageHandler
+}
+
+// route parses and handles an event already decoded as an envelope.
+type route func(Envelope[json.RawMessage]) error
+
+// NewRouter creates a new [Router] serving events from the given raw subscription, which is owned by the router
+// (it should not be used directly anymore). The options are the same ones used by [NewSubscription].
+// Handlers must be registered with [RegisterHandler] before calling [Router.Serve].
+func NewRouter(rawsub *MessageSubscription, options ...SubscriptionOption) *Router {
+	r := &Router{
+		rawsub: rawsub,
+		opts: subscriptionOptions{
+			traceIDGenerator:    uuid.NewString,
+			maxDecompressedSize: DefaultMaxDecompressedSize,
+		},
+		routes: map[string]route{},
+	}
+	for _, option := range options {
+		option(&r.opts)
+	}
+	rawsub.ackOnPanic = r.opts.ackOnPanic
+	return r
+}
+
+// RegisterHandler registers the handler for events with the given name on the router.
+// The handler is called with the event parsed as [T], with the same context as handlers of [Subscription.Serve].
+// Handlers must be registered before calling [Router.Serve].
+// It panics if name is empty or if a handler is already registered for the name.
+func RegisterHandler[T any](r *Router, name string, handler Handler[T]) {
+	if name == "" {
+		panic("event: registering router handler: event name can't be empty")
+	}
+	if _, ok := r.routes[name]; ok {
+		panic(fmt.Errorf("event: registering router handler: handler for %q already registered", name))
+	}
+	r.routes[name] = func(envelope Envelope[json.RawMessage]) error {
+		return handle(r, envelope, handler)
+	}
+}
+
+// SetFallback sets the handler called for events with names that have no registered handler.
+// The message body is always the uncompressed event [Envelope], see [DecodeEnvelope].
+// By default these events are discarded as malformed and a Nack is sent, like events with the wrong name on a [Subscription].
+// Metrics are sampled by the router, so the handler doesn't need to be wrapped with [SampledMessageHandler].
+// Must be called before calling [Router.Serve].
+func (r *Router) SetFallback(handler MessageHandler) {
+	r.fallback = handler
+}
+
+// Serve will start serving all events from the subscription, calling the handler registered for the name of each event.
+// It will run until [Router.Shutdown] is called and it acknowledges events like [Subscription.Serve].
+// Malformed events (invalid JSON or events that can't be parsed as the type of their handler) are discarded
+// and a Nack is sent automatically.
+// Metrics are sampled for every message, including malformed ones (with an empty name if the envelope can't be parsed).
+func (r *Router) Serve() error {
+	return r.rawsub.Serve(func(msg Message) error {
+		start := time.Now()
+		name, err := r.dispatch(msg)
+		// The message is kept as received (compressed) for metrics, like on [Subscription.Serve].
+		sampleProcess(msg, name, time.Since(start), err)
+		return err
+	})
+}
+
+// dispatch calls the handler registered for the event of the given message, returning the name of the event
+// (empty if it can't be parsed).
+func (r *Router) dispatch(msg Message) (string, error) {
+	body, err := decompressBody(msg, r.opts.maxDecompressedSize)
+	if err != nil {
+		r.rawsub.stats.malformed.Add(1)
+		slog.Error("decompressing event body", "error", err, "metadata", msg.Metadata)
+		return "", err
+	}
+
+	envelope, err := DecodeEnvelope(body)
+	if err != nil {
+		r.rawsub.stats.malformed.Add(1)
+		slog.Error("parsing event body", "error", err, "body", string(body))
+		return "", err
+	}
+
+	route, ok := r.routes[envelope.Name]
+	if ok {
+		return envelope.Name, route(envelope)
+	}
+	if r.fallback != nil {
+		msg.Body = body
+		return envelope.Name, r.fallback(msg)
+	}
+	r.rawsub.stats.malformed.Add(1)
+	slog.Error("event name has no router handler", "received", envelope.Name)
+	return envelope.Name, fmt.Errorf("no handler for event %q", envelope.Name)
+}
+
+// Shutdown will shutdown the router, stopping any calls to [Router.Serve].
+// The router should not be used after this method is called.
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.rawsub.Shutdown(ctx)
+}
+
+// handle parses the event of the given envelope as [T] and calls the handler with it.
+// It is a function since methods can't have type parameters.
+func handle[T any](r *Router, envelope Envelope[json.RawMessage], handler Handler[T]) error {
+	var event T
+	// Like on [Subscription.Serve], a missing event is the zero value
+	if len(envelope.Event) > 0 {
+		if err := r.opts.unmarshal(envelope.Event, &event); err != nil {
+			r.rawsub.stats.malformed.Add(1)
+			slog.Error("parsing event", "name", envelope.Name, "error", err, "event", string(envelope.Event))
+			return fmt.Errorf("parsing event %q: %w", envelope.Name, err)
+		}
+	}
+	if envelope.TraceID == "" {
+		envelope.TraceID = r.opts.traceIDGenerator()
+	}
+	return handler(newEventContext(envelope.TraceID, envelope.OrgID, envelope.RequestID), event)
+}
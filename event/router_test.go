@@ -0,0 +1,207 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"github.com/birdie-ai/golibs/tracing"
+	"gocloud.dev/pubsub"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	rawsub, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := event.NewRouter(rawsub)
+
+	type (
+		Created struct {
+			ID string `json:"id"`
+		}
+		handled struct {
+			Name  string
+			Event any
+			OrgID string
+		}
+	)
+	got := make(chan handled)
+	event.RegisterHandler(router, "created", func(ctx context.Context, e Created) error {
+		got <- handled{"created", e, tracing.CtxGetOrgID(ctx)}
+		return nil
+	})
+	event.RegisterHandler(router, "deleted", func(ctx context.Context, id int) error {
+		got <- handled{"deleted", id, tracing.CtxGetOrgID(ctx)}
+		return nil
+	})
+	router.SetFallback(func(msg event.Message) error {
+		envelope, err := event.DecodeEnvelope(msg.Body)
+		if err != nil {
+			return err
+		}
+		got <- handled{"fallback", envelope.Name, ""}
+		return nil
+	})
+
+	servingDone := make(chan struct{})
+	go func() {
+		err := router.Serve()
+		t.Logf("router.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	ctx = tracing.CtxWithOrgID(ctx, "org")
+	if err := event.NewPublisher[Created]("created", topic).Publish(ctx, Created{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := event.NewPublisher[int]("deleted", topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := event.NewPublisher[int]("unknown", topic).Publish(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delivery order is not guaranteed
+	gotHandled := map[string]handled{}
+	for range 3 {
+		h := <-got
+		gotHandled[h.Name] = h
+	}
+	assertEqual(t, gotHandled, map[string]handled{
+		"created":  {"created", Created{ID: "a"}, "org"},
+		"deleted":  {"deleted", 1, "org"},
+		"fallback": {"fallback", "unknown", ""},
+	})
+
+	// Ack happens after the handler returns, it is async.
+	deadline := time.Now().Add(time.Second)
+	for rawsub.Stats().Acked < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assertEqual(t, rawsub.Stats().Acked, uint64(3))
+
+	if err := router.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-servingDone
+}
+
+func TestRouterDiscardsUnknownAndMalformedEvents(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	rawsub, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := event.NewRouter(rawsub)
+	event.RegisterHandler(router, "number", func(context.Context, int) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	if err := event.NewPublisher[string]("number", topic).Publish(ctx, "not a number"); err != nil {
+		t.Fatal(err)
+	}
+	if err := event.NewPublisher[int]("unknown", topic).Publish(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	servingDone := make(chan struct{})
+	go func() {
+		err := router.Serve()
+		t.Logf("router.Serve error: %v", err)
+		close(servingDone)
+	}()
+	defer func() {
+		shutdown(t, router)
+		<-servingDone
+	}()
+
+	// Nacked messages are redelivered, so there may be more than 2 malformed messages
+	deadline := time.Now().Add(time.Second)
+	for rawsub.Stats().Malformed < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := rawsub.Stats()
+	if stats.Malformed < 2 || stats.Acked != 0 {
+		t.Fatalf("got stats %+v; want at least 2 malformed and no acked", stats)
+	}
+}
+
+func TestRouterMissingEventIsZeroValue(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	rawsub, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := event.NewRouter(rawsub, event.SubscriptionWithStrictDecoding())
+
+	type Created struct {
+		ID string `json:"id"`
+	}
+	got := make(chan Created)
+	event.RegisterHandler(router, "created", func(_ context.Context, e Created) error {
+		got <- e
+		return nil
+	})
+
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte(`{"name":"created"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	servingDone := make(chan struct{})
+	go func() {
+		err := router.Serve()
+		t.Logf("router.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	assertEqual(t, <-got, Created{})
+
+	shutdown(t, router)
+	<-servingDone
+	assertEqual(t, rawsub.Stats().Malformed, uint64(0))
+}
+
+func TestRegisterHandlerTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic registering handler twice, got none")
+		}
+	}()
+	router := event.NewRouter(nil)
+	event.RegisterHandler(router, "name", func(context.Context, int) error { return nil })
+	event.RegisterHandler(router, "name", func(context.Context, int) error { return nil })
+}
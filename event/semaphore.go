@@ -0,0 +1,47 @@
+package event
+
+import "sync"
+
+// semaphore limits the amount of concurrent holders, like a channel based semaphore,
+// but its limit can be changed while it is being used.
+type semaphore struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	acquired int
+}
+
+func newSemaphore(limit int) *semaphore {
+	s := &semaphore{limit: limit}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire blocks until the amount of holders is below the limit.
+func (s *semaphore) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.acquired >= s.limit {
+		s.cond.Wait()
+	}
+	s.acquired++
+}
+
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.acquired--
+	s.cond.Broadcast()
+}
+
+// setLimit changes the limit of the semaphore. If the limit is reduced below the current
+// amount of holders no holder is affected, new ones will wait until enough holders release it.
+func (s *semaphore) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	s.cond.Broadcast()
+}
@@ -0,0 +1,47 @@
+package event
+
+import "sync/atomic"
+
+type (
+	// SubscriptionStats is a snapshot of the runtime counters of a subscription.
+	// It is useful for lightweight introspection (and tests), see [MustRegisterMetrics] for proper metrics.
+	SubscriptionStats struct {
+		// Received is the total of messages received.
+		Received uint64
+		// Acked is the total of messages acked.
+		Acked uint64
+		// Nacked is the total of messages nacked.
+		Nacked uint64
+		// Malformed is the total of messages that could not be decompressed/parsed as events (or had the wrong event name).
+		Malformed uint64
+		// Panics is the total of handler calls that panicked.
+		Panics uint64
+	}
+
+	subscriptionStats struct {
+		received  atomic.Uint64
+		acked     atomic.Uint64
+		nacked    atomic.Uint64
+		malformed atomic.Uint64
+		panics    atomic.Uint64
+	}
+)
+
+// Stats returns a snapshot of the runtime counters of the subscription.
+// It is safe to call Stats concurrently with the subscription being served.
+func (s *Subscription[T]) Stats() SubscriptionStats {
+	return s.rawsub.Stats()
+}
+
+// Stats returns a snapshot of the runtime counters of the subscription.
+// It is safe to call Stats concurrently with the subscription being served.
+// Messages are never considered malformed by a [MessageSubscription] since it makes no assumptions about them.
+func (r *MessageSubscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Received:  r.stats.received.Load(),
+		Acked:     r.stats.acked.Load(),
+		Nacked:    r.stats.nacked.Load(),
+		Malformed: r.stats.malformed.Load(),
+		Panics:    r.stats.panics.Load(),
+	}
+}
@@ -0,0 +1,117 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/event"
+	"gocloud.dev/pubsub"
+)
+
+func TestSubscriptionStats(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	const eventName = "test"
+
+	subscription, err := event.NewSubscription[int](eventName, url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, subscription)
+
+	assertEqual(t, subscription.Stats(), event.SubscriptionStats{})
+
+	publisher := event.NewPublisher[int](eventName, topic)
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := subscription.ReceiveN(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events[0].Ack()
+
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte("malformed")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := subscription.Receive(ctx); err == nil {
+		t.Fatal("want error receiving malformed event, got nil")
+	}
+
+	// Nack only after receiving the malformed event, since the Nacked event may be redelivered right away
+	events[1].Nack()
+
+	// Malformed events received with Receive are not Nacked automatically
+	assertEqual(t, subscription.Stats(), event.SubscriptionStats{
+		Received:  3,
+		Acked:     1,
+		Nacked:    1,
+		Malformed: 1,
+	})
+}
+
+func TestRawSubscriptionStatsPanics(t *testing.T) {
+	t.Parallel()
+
+	url := newTopicURL(t)
+	ctx := context.Background()
+
+	topic, err := pubsub.OpenTopic(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, topic)
+
+	subscription, err := event.NewRawSubscription(url, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan struct{})
+	servingDone := make(chan struct{})
+	go func() {
+		err := subscription.Serve(func(event.Message) error {
+			select {
+			case handled <- struct{}{}:
+			default:
+			}
+			panic("oh no")
+		})
+		t.Logf("rawsubscription.Serve error: %v", err)
+		close(servingDone)
+	}()
+
+	if err := topic.Send(ctx, &pubsub.Message{Body: []byte("msg")}); err != nil {
+		t.Fatal(err)
+	}
+	<-handled
+
+	// The panic is accounted after the handler returns, it is async. The Nack happens after that.
+	deadline := time.Now().Add(time.Second)
+	for subscription.Stats().Nacked == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	stats := subscription.Stats()
+	if stats.Received == 0 || stats.Panics == 0 || stats.Nacked == 0 {
+		t.Fatalf("got stats %+v; want received, panics and nacked", stats)
+	}
+
+	if err := subscription.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down subscription: %v", err)
+	}
+	<-servingDone
+}
@@ -0,0 +1,34 @@
+// Package tracectx stores tracing information on contexts.
+// It is shared by the tracing and slog packages, users should use the tracing package instead.
+package tracectx
+
+import "context"
+
+// Key identifies a tracing value stored on a context.
+type Key int
+
+// All tracing values stored on contexts.
+const (
+	TraceIDKey Key = iota
+	OrgIDKey
+	UserIDKey
+	RequestIDKey
+)
+
+// With creates a new [context.Context] with the given value associated with the given key.
+func With(ctx context.Context, k Key, val string) context.Context {
+	return context.WithValue(ctx, k, val)
+}
+
+// Get gets the value associated with the given key on the context, or empty if there is none.
+func Get(ctx context.Context, k Key) string {
+	val := ctx.Value(k)
+	if val == nil {
+		return ""
+	}
+	str, ok := val.(string)
+	if !ok {
+		return ""
+	}
+	return str
+}
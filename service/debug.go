@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"strings"
+
+	"github.com/birdie-ai/golibs/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// DebugMuxOption is used to configure the mux created by [NewDebugMux].
+	DebugMuxOption func(*debugMuxConfig)
+
+	// BuildInfo is the build information of a service, as served by the mux created with [NewDebugMux].
+	BuildInfo struct {
+		GoVersion string `json:"go_version"`
+		Revision  string `json:"revision"`
+	}
+
+	debugMuxConfig struct {
+		prefix string
+		pprof  bool
+	}
+)
+
+// NewDebugMux creates a [http.ServeMux] with common operational endpoints of services:
+//
+//   - /metrics : Prometheus metrics of the given registry.
+//   - /debug/buildinfo : the [BuildInfo] of the service as JSON.
+//   - /debug/pprof/ : Go's [pprof] handlers (only if enabled with [DebugMuxWithPprof]).
+//
+// The endpoints can be served under a prefix with [DebugMuxWithPrefix].
+func NewDebugMux(registry *prometheus.Registry, options ...DebugMuxOption) *http.ServeMux {
+	var cfg debugMuxConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/debug/buildinfo", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ReadBuildInfo()); err != nil {
+			slog.FromCtx(req.Context()).Debug("service: writing build info response", "error", err)
+		}
+	})
+	if cfg.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	if cfg.prefix == "" {
+		return mux
+	}
+	prefixMux := http.NewServeMux()
+	prefixMux.Handle(cfg.prefix+"/", http.StripPrefix(cfg.prefix, mux))
+	return prefixMux
+}
+
+// DebugMuxWithPrefix configures the mux created by [NewDebugMux] to serve all endpoints under the given path prefix,
+// like "/ops" serving the build info on "/ops/debug/buildinfo".
+func DebugMuxWithPrefix(prefix string) DebugMuxOption {
+	return func(cfg *debugMuxConfig) {
+		cfg.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// DebugMuxWithPprof configures the mux created by [NewDebugMux] to serve Go's [pprof] handlers.
+// They are not served by default since they expose a lot of details about the service.
+func DebugMuxWithPprof() DebugMuxOption {
+	return func(cfg *debugMuxConfig) {
+		cfg.pprof = true
+	}
+}
+
+// ReadBuildInfo reads the [BuildInfo] of the running service.
+// Information that is not available is "undefined".
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		GoVersion: "undefined",
+		Revision:  "undefined",
+	}
+
+	goBuildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = goBuildInfo.GoVersion
+	for _, buildSetting := range goBuildInfo.Settings {
+		if buildSetting.Key == "vcs.revision" {
+			info.Revision = buildSetting.Value
+			if len(info.Revision) > 7 {
+				// Useful for git revisions (short hash)
+				info.Revision = info.Revision[0:7]
+			}
+		}
+	}
+	return info
+}
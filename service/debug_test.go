@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/birdie-ai/golibs/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDebugMux(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	service.MustRegisterMetrics(registry)
+	service.SampleBuildInfo()
+
+	cases := []struct {
+		name       string
+		options    []service.DebugMuxOption
+		path       string
+		wantStatus int
+	}{
+		{name: "metrics", path: "/metrics", wantStatus: http.StatusOK},
+		{name: "build info", path: "/debug/buildinfo", wantStatus: http.StatusOK},
+		{name: "pprof disabled", path: "/debug/pprof/", wantStatus: http.StatusNotFound},
+		{name: "pprof enabled", options: []service.DebugMuxOption{service.DebugMuxWithPprof()}, path: "/debug/pprof/", wantStatus: http.StatusOK},
+		{name: "prefix", options: []service.DebugMuxOption{service.DebugMuxWithPrefix("/ops/")}, path: "/ops/debug/buildinfo", wantStatus: http.StatusOK},
+		{name: "prefix no match", options: []service.DebugMuxOption{service.DebugMuxWithPrefix("/ops")}, path: "/debug/buildinfo", wantStatus: http.StatusNotFound},
+		{
+			name:       "prefix pprof",
+			options:    []service.DebugMuxOption{service.DebugMuxWithPrefix("/ops"), service.DebugMuxWithPprof()},
+			path:       "/ops/debug/pprof/",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mux := service.NewDebugMux(registry, c.options...)
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if res.Code != c.wantStatus {
+				t.Fatalf("got status %d; want %d", res.Code, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDebugMuxBuildInfo(t *testing.T) {
+	mux := service.NewDebugMux(prometheus.NewRegistry())
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil))
+
+	var got service.BuildInfo
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := service.ReadBuildInfo(); got != want {
+		t.Fatalf("got build info %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// InFlightTracker tracks HTTP requests being handled, so a service can wait for all of them to finish
+// before shutting down the dependencies used to handle requests (like database connections).
+// Requests are tracked by wrapping handlers with [InFlightTracker.Handler].
+type InFlightTracker struct {
+	mutex  sync.Mutex
+	active int
+	idle   chan struct{}
+}
+
+// NewInFlightTracker creates a new [InFlightTracker] with no requests in flight.
+func NewInFlightTracker() *InFlightTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &InFlightTracker{idle: idle}
+}
+
+// Handler wraps the given handler, tracking the requests it handles.
+// A request is in flight until the given handler returns.
+func (t *InFlightTracker) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		t.start()
+		defer t.done()
+		next.ServeHTTP(res, req)
+	})
+}
+
+// Active returns how many requests are in flight.
+func (t *InFlightTracker) Active() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.active
+}
+
+// Wait blocks until there are no requests in flight or the given context is done, in which case
+// an error wrapping the context error is returned. New requests can start while waiting, so the
+// server should stop accepting requests before calling Wait (like with [http.Server.Shutdown]).
+func (t *InFlightTracker) Wait(ctx context.Context) error {
+	t.mutex.Lock()
+	idle := t.idle
+	t.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for %d in flight requests: %w", t.Active(), ctx.Err())
+	}
+}
+
+// Shutdown is the same as [InFlightTracker.Wait], so the tracker can be added to a [ShutdownHandler].
+func (t *InFlightTracker) Shutdown(ctx context.Context) error {
+	return t.Wait(ctx)
+}
+
+func (t *InFlightTracker) start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.active == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.active++
+}
+
+func (t *InFlightTracker) done() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.active--
+	if t.active == 0 {
+		close(t.idle)
+	}
+}
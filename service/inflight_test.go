@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/service"
+)
+
+func TestInFlightTracker(t *testing.T) {
+	tracker := service.NewInFlightTracker()
+
+	// No requests in flight, no wait
+	if err := tracker.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := tracker.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	const requests = 3
+	handled := make(chan struct{})
+	for range requests {
+		go func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			handled <- struct{}{}
+		}()
+		<-started
+	}
+	if got := tracker.Active(); got != requests {
+		t.Fatalf("got %d active requests; want %d", got, requests)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	waitErr := make(chan error)
+	go func() {
+		waitErr <- tracker.Shutdown(context.Background())
+	}()
+
+	for range requests {
+		release <- struct{}{}
+		<-handled
+	}
+	if err := <-waitErr; err != nil {
+		t.Fatal(err)
+	}
+	if got := tracker.Active(); got != 0 {
+		t.Fatalf("got %d active requests; want 0", got)
+	}
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"net/http"
-	"runtime/debug"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -40,26 +39,10 @@ func InstrumentHTTPByPath(handler http.Handler, path string) http.Handler {
 // SampleBuildInfo creates a sample of the service_build_info metric.
 // Since it is a gauge it needs to be set only once on the service startup.
 func SampleBuildInfo() {
-	goVersion := "undefined"
-	revision := "undefined"
-
-	goBuildInfo, ok := debug.ReadBuildInfo()
-	if ok {
-		goVersion = goBuildInfo.GoVersion
-		for _, buildSetting := range goBuildInfo.Settings {
-			if buildSetting.Key == "vcs.revision" {
-				revision = buildSetting.Value
-				if len(revision) > 7 {
-					// Useful for git revisions (short hash)
-					revision = revision[0:7]
-				}
-			}
-		}
-	}
-
+	info := ReadBuildInfo()
 	labels := prometheus.Labels{
-		"goversion": goVersion,
-		"revision":  revision,
+		"goversion": info.GoVersion,
+		"revision":  info.Revision,
 	}
 	buildInfo.With(labels).Set(1.0)
 }
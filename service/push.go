@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/birdie-ai/golibs/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+type (
+	// PushOption is used to configure how metrics are pushed by [PushMetrics] and [NewMetricsPusher].
+	PushOption func(*pushConfig)
+
+	// MetricsPusher periodically pushes metrics to a Prometheus Pushgateway, see [NewMetricsPusher].
+	MetricsPusher struct {
+		pusher *push.Pusher
+		// ctx is used by the periodic pushes, it is cancelled on shutdown.
+		ctx    context.Context
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	pushConfig struct {
+		user, pass string
+		header     http.Header
+		client     push.HTTPDoer
+		grouping   map[string]string
+	}
+)
+
+// PushMetrics pushes all metrics of the given registry to the Prometheus Pushgateway on the given URL,
+// replacing all metrics previously pushed with the same job name (and grouping, see [PushWithGrouping]).
+// It is intended for short lived jobs that exit before they can be scraped.
+// For longer jobs see [NewMetricsPusher].
+func PushMetrics(ctx context.Context, gatewayURL, jobName string, registry *prometheus.Registry, options ...PushOption) error {
+	if err := newPusher(gatewayURL, jobName, registry, options).PushContext(ctx); err != nil {
+		return fmt.Errorf("pushing metrics of job %q: %w", jobName, err)
+	}
+	return nil
+}
+
+// NewMetricsPusher creates a [MetricsPusher] that pushes all metrics of the given registry to the Prometheus
+// Pushgateway on the given URL (like [PushMetrics]) every period, until [MetricsPusher.Shutdown] is called.
+// Push failures are only logged, so a job is not affected by the Pushgateway being unavailable.
+// It implements [Shutdowner], so it can be added to a [ShutdownHandler].
+// It returns an error if the period is not positive.
+func NewMetricsPusher(gatewayURL, jobName string, registry *prometheus.Registry, period time.Duration, options ...PushOption) (*MetricsPusher, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("service: invalid metrics push period %v: must be positive", period)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &MetricsPusher{
+		pusher: newPusher(gatewayURL, jobName, registry, options),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.pusher.PushContext(p.ctx); err != nil && p.ctx.Err() == nil {
+					slog.Warn("service: pushing metrics", "job", jobName, "error", err)
+				}
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+	return p, nil
+}
+
+// Shutdown stops pushing metrics periodically and pushes them one last time, so the final values of the metrics
+// are always pushed. A periodic push in progress is cancelled, and waiting for it or the last push are cancelled
+// if the given context is cancelled.
+func (p *MetricsPusher) Shutdown(ctx context.Context) error {
+	p.cancel()
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting periodic metrics push: %w", ctx.Err())
+	}
+	if err := p.pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("pushing metrics on shutdown: %w", err)
+	}
+	return nil
+}
+
+// PushWithBasicAuth configures pushes to use HTTP Basic Authentication with the given credentials.
+func PushWithBasicAuth(user, pass string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.user = user
+		cfg.pass = pass
+	}
+}
+
+// PushWithBearer configures pushes to authenticate with the given bearer token.
+func PushWithBearer(token string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// PushWithClient configures the HTTP client used to push metrics, like a client created by xhttp.NewRetrierClient.
+// Defaults to [http.DefaultClient].
+func PushWithClient(client push.HTTPDoer) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.client = client
+	}
+}
+
+// PushWithGrouping adds a grouping label to pushed metrics, like an instance label, so multiple instances
+// of the same job don't replace each other metrics.
+func PushWithGrouping(name, value string) PushOption {
+	return func(cfg *pushConfig) {
+		cfg.grouping[name] = value
+	}
+}
+
+func newPusher(gatewayURL, jobName string, registry *prometheus.Registry, options []PushOption) *push.Pusher {
+	cfg := pushConfig{
+		header:   http.Header{},
+		grouping: map[string]string{},
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	pusher := push.New(gatewayURL, jobName).Gatherer(registry).Header(cfg.header)
+	if cfg.user != "" || cfg.pass != "" {
+		pusher = pusher.BasicAuth(cfg.user, cfg.pass)
+	}
+	if cfg.client != nil {
+		pusher = pusher.Client(cfg.client)
+	}
+	for name, value := range cfg.grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	return pusher
+}
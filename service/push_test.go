@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type pushedRequest struct {
+	method, path, auth, body string
+}
+
+func newFakePushgateway(t *testing.T) (*httptest.Server, func() []pushedRequest) {
+	t.Helper()
+
+	var (
+		mutex    sync.Mutex
+		requests []pushedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("reading push body: %v", err)
+		}
+		mutex.Lock()
+		requests = append(requests, pushedRequest{req.Method, req.URL.Path, req.Header.Get("Authorization"), string(body)})
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []pushedRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]pushedRequest(nil), requests...)
+	}
+}
+
+func newPushRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_job_runs_total", Help: "test"})
+	registry.MustRegister(counter)
+	counter.Inc()
+	return registry
+}
+
+func TestPushMetrics(t *testing.T) {
+	server, pushed := newFakePushgateway(t)
+
+	err := service.PushMetrics(context.Background(), server.URL, "test-job", newPushRegistry(t),
+		service.PushWithBearer("token"),
+		service.PushWithGrouping("instance", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := pushed()
+	if len(requests) != 1 {
+		t.Fatalf("got %d pushes; want 1", len(requests))
+	}
+	got := requests[0]
+	if got.method != http.MethodPut {
+		t.Errorf("got method %q; want %q", got.method, http.MethodPut)
+	}
+	if want := "/metrics/job/test-job/instance/a"; got.path != want {
+		t.Errorf("got path %q; want %q", got.path, want)
+	}
+	if got.auth != "Bearer token" {
+		t.Errorf("got Authorization %q; want bearer token", got.auth)
+	}
+	if !strings.Contains(got.body, "test_job_runs_total") {
+		t.Errorf("pushed metrics missing test metric: %q", got.body)
+	}
+}
+
+func TestPushMetricsBasicAuth(t *testing.T) {
+	server, pushed := newFakePushgateway(t)
+
+	err := service.PushMetrics(context.Background(), server.URL, "test-job", newPushRegistry(t),
+		service.PushWithBasicAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", pushed()[0].auth)
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Fatalf("got basic auth %q:%q (ok=%v); want user:pass", user, pass, ok)
+	}
+}
+
+func TestPushMetricsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := service.PushMetrics(context.Background(), server.URL, "test-job", newPushRegistry(t)); err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func TestMetricsPusher(t *testing.T) {
+	server, pushed := newFakePushgateway(t)
+
+	pusher, err := service.NewMetricsPusher(server.URL, "test-job", newPushRegistry(t), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(pushed()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(pushed()) < 2 {
+		t.Fatalf("got %d periodic pushes; want at least 2", len(pushed()))
+	}
+
+	if err := pusher.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	afterShutdown := len(pushed())
+	time.Sleep(10 * time.Millisecond)
+	if got := len(pushed()); got != afterShutdown {
+		t.Fatalf("got %d pushes after shutdown; want %d", got, afterShutdown)
+	}
+}
+
+func TestMetricsPusherInvalidPeriod(t *testing.T) {
+	for _, period := range []time.Duration{0, -time.Second} {
+		if _, err := service.NewMetricsPusher("http://localhost", "test-job", newPushRegistry(t), period); err == nil {
+			t.Fatalf("period %v: want error, got nil", period)
+		}
+	}
+}
+
+func TestMetricsPusherShutdownTimeout(t *testing.T) {
+	// The Pushgateway only answers after the test, so pushes only finish when cancelled.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	pusher, err := service.NewMetricsPusher(server.URL, "test-job", newPushRegistry(t), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give time for a periodic push to start.
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := pusher.Shutdown(ctx); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown took %v; want it to respect the context timeout", elapsed)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+// SignalContext creates a [context.Context] that is cancelled when the process receives SIGINT or SIGTERM,
+// usually used to start shutting down a service (like with [ShutdownHandler.Wait]).
+// If a second signal is received, while shutting down, the process exits immediately (with exit code 1),
+// avoiding hung shutdowns. Call the returned [context.CancelFunc] to stop listening for signals,
+// usually deferred, after that signals are handled as if SignalContext was never called.
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	cancel := func() {
+		stopOnce.Do(func() {
+			signal.Stop(signals)
+			close(stop)
+			cancelCtx()
+		})
+	}
+
+	go func() {
+		select {
+		case sig := <-signals:
+			slog.Info("service: received signal, shutting down (send it again to force exit)", "signal", sig.String())
+			cancelCtx()
+		case <-stop:
+			return
+		}
+
+		select {
+		case sig := <-signals:
+			slog.Error("service: received signal while shutting down, forcing exit", "signal", sig.String())
+			os.Exit(1)
+		case <-stop:
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"os"
+	"os/signal"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/service"
+)
+
+func TestSignalContext(t *testing.T) {
+	// Listening for the signal on the test ensures that signals never kill the test process:
+	// not by the default signal handling and not by a second signal forcing the exit.
+	testSignals := make(chan os.Signal, 1)
+	signal.Notify(testSignals, os.Interrupt)
+	defer signal.Stop(testSignals)
+
+	ctx, cancel := service.SignalContext()
+	defer cancel()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Skipf("sending signals not supported: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for context to be cancelled by signal")
+	}
+	receiveSignal(t, testSignals)
+
+	// After cancel a second signal doesn't force the exit anymore.
+	cancel()
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	receiveSignal(t, testSignals)
+}
+
+func TestSignalContextCancel(t *testing.T) {
+	ctx, cancel := service.SignalContext()
+	cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for context to be cancelled")
+	}
+}
+
+func receiveSignal(t *testing.T, signals <-chan os.Signal) {
+	t.Helper()
+
+	select {
+	case <-signals:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for signal")
+	}
+}
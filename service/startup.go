@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sourcegraph/conc/pool"
+)
+
+// StartFunc starts a service (or any dependency of a service, like running migrations).
+type StartFunc func(context.Context) error
+
+// Starter handles the ordered startup of multiple services.
+// Services are started in phases, phases run in ascending order and each phase only starts after
+// all services of the previous phase started successfully. Services on the same phase are started concurrently.
+// It complements [ShutdownHandler], handling the startup part of the service lifecycle.
+type Starter struct {
+	waitPeriod time.Duration
+	phases     map[int][]starterEntry
+}
+
+type starterEntry struct {
+	start      StartFunc
+	shutdowner Shutdowner
+}
+
+// NewStarter creates a new [Starter]. The given [gracefulShutdownPeriod] is used when
+// shutting down already started services after a startup failure.
+func NewStarter(gracefulShutdownPeriod time.Duration) *Starter {
+	return &Starter{
+		waitPeriod: gracefulShutdownPeriod,
+		phases:     map[int][]starterEntry{},
+	}
+}
+
+// Add will add the given start function to the given phase.
+// Must be called before [Starter.Start] is called.
+func (s *Starter) Add(phase int, start StartFunc) {
+	s.AddWithShutdown(phase, start, nil)
+}
+
+// AddWithShutdown will add the given start function to the given phase, like [Starter.Add].
+// If the start function succeeds but the startup fails later (on the same phase or a later one)
+// the given [Shutdowner] is used to shut down the started service.
+// Must be called before [Starter.Start] is called.
+func (s *Starter) AddWithShutdown(phase int, start StartFunc, shutdowner Shutdowner) {
+	s.phases[phase] = append(s.phases[phase], starterEntry{
+		start:      start,
+		shutdowner: shutdowner,
+	})
+}
+
+// Start will start all services, phase by phase in ascending order.
+// All services of a phase are started concurrently and the next phase only starts when all of them succeeded.
+// If any service fails to start the remaining phases are not started and all services that were started
+// successfully are shut down, in reverse phase order, waiting for each of them the period
+// provided on [NewStarter]. All start and shutdown errors are returned.
+// If [ctx] is cancelled between phases the startup is aborted the same way.
+func (s *Starter) Start(ctx context.Context) error {
+	phases := make([]int, 0, len(s.phases))
+	for phase := range s.phases {
+		phases = append(phases, phase)
+	}
+	slices.Sort(phases)
+
+	var started [][]Shutdowner
+
+	for _, phase := range phases {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(fmt.Errorf("starting phase %d: %w", phase, err), s.rollback(started))
+		}
+
+		entries := s.phases[phase]
+		errs := make([]error, len(entries))
+		p := pool.New()
+
+		for i, v := range entries {
+			entry := v
+			p.Go(func() {
+				errs[i] = entry.start(ctx)
+			})
+		}
+		p.Wait()
+
+		var phaseStarted []Shutdowner
+		for i, entry := range entries {
+			if errs[i] == nil && entry.shutdowner != nil {
+				phaseStarted = append(phaseStarted, entry.shutdowner)
+			}
+		}
+		started = append(started, phaseStarted)
+
+		if err := errors.Join(errs...); err != nil {
+			return errors.Join(fmt.Errorf("starting phase %d: %w", phase, err), s.rollback(started))
+		}
+	}
+
+	return nil
+}
+
+// rollback shuts down the given started services in reverse phase order.
+func (s *Starter) rollback(started [][]Shutdowner) error {
+	var errs []error
+
+	for i := len(started) - 1; i >= 0; i-- {
+		p := pool.NewWithResults[error]()
+
+		for _, v := range started[i] {
+			service := v
+
+			p.Go(func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), s.waitPeriod)
+				defer cancel()
+				return service.Shutdown(ctx)
+			})
+		}
+
+		errs = append(errs, p.Wait()...)
+	}
+
+	return errors.Join(errs...)
+}
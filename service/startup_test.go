@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/service"
+)
+
+func TestStarterPhases(t *testing.T) {
+	starter := service.NewStarter(time.Minute)
+
+	var (
+		mutex   sync.Mutex
+		started []string
+	)
+	start := func(name string) service.StartFunc {
+		return func(context.Context) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			started = append(started, name)
+			return nil
+		}
+	}
+
+	// Services on the same phase must start concurrently, so both will only
+	// return when the other one was also called.
+	phase1a := make(chan struct{})
+	phase1b := make(chan struct{})
+
+	starter.Add(2, start("phase 2"))
+	starter.Add(1, func(ctx context.Context) error {
+		close(phase1a)
+		<-phase1b
+		return start("phase 1")(ctx)
+	})
+	starter.Add(1, func(ctx context.Context) error {
+		close(phase1b)
+		<-phase1a
+		return start("phase 1")(ctx)
+	})
+	starter.Add(-1, start("phase -1"))
+
+	if err := starter.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"phase -1", "phase 1", "phase 1", "phase 2"}
+	if len(started) != len(want) {
+		t.Fatalf("got started %v; want %v", started, want)
+	}
+	for i := range want {
+		if started[i] != want[i] {
+			t.Fatalf("got started %v; want %v", started, want)
+		}
+	}
+}
+
+func TestStarterFailureRollback(t *testing.T) {
+	starter := service.NewStarter(time.Minute)
+
+	phase1Service := &countShutdowner{}
+	phase2Service := &countShutdowner{}
+	failedService := &countShutdowner{}
+	notStartedService := &countShutdowner{}
+	notStartedCalled := false
+
+	startErr := errors.New("start error")
+	shutdownErr := errors.New("shutdown error")
+	phase1Service.err = shutdownErr
+
+	ok := func(context.Context) error { return nil }
+
+	starter.AddWithShutdown(1, ok, phase1Service)
+	starter.Add(1, ok)
+	starter.AddWithShutdown(2, ok, phase2Service)
+	starter.AddWithShutdown(2, func(context.Context) error { return startErr }, failedService)
+	starter.AddWithShutdown(3, func(context.Context) error {
+		notStartedCalled = true
+		return nil
+	}, notStartedService)
+
+	err := starter.Start(context.Background())
+	if !errors.Is(err, startErr) {
+		t.Fatalf("got error %v; want %v", err, startErr)
+	}
+	if !errors.Is(err, shutdownErr) {
+		t.Fatalf("got error %v; want %v", err, shutdownErr)
+	}
+	if notStartedCalled {
+		t.Fatal("phase after failure should not be started")
+	}
+
+	assertShutdownCalls := func(name string, s *countShutdowner, want int) {
+		t.Helper()
+		if s.calls != want {
+			t.Errorf("%s: got %d shutdown calls; want %d", name, s.calls, want)
+		}
+	}
+	assertShutdownCalls("phase 1", phase1Service, 1)
+	assertShutdownCalls("phase 2", phase2Service, 1)
+	assertShutdownCalls("failed", failedService, 0)
+	assertShutdownCalls("not started", notStartedService, 0)
+}
+
+func TestStarterCancelledContext(t *testing.T) {
+	starter := service.NewStarter(time.Minute)
+	called := false
+	starter.Add(1, func(context.Context) error {
+		called = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := starter.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+	if called {
+		t.Fatal("start function should not be called with cancelled context")
+	}
+}
+
+type countShutdowner struct {
+	calls int
+	err   error
+}
+
+func (c *countShutdowner) Shutdown(context.Context) error {
+	c.calls++
+	return c.err
+}
@@ -0,0 +1,70 @@
+package slog
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// maxErrorCauses is the max amount of causes logged by [ErrorAttr], avoiding huge (or infinite, on cycles) error chains.
+const maxErrorCauses = 32
+
+// ErrorAttr creates an "error" [Attr] that logs the error as a structured object with its message, its type and
+// all the errors it wraps (found with Unwrap, including joined errors), like:
+//
+//	{"message": "loading config: open config.json: no such file", "type": "*fmt.wrapError", "causes": [
+//	  {"message": "open config.json: no such file", "type": "*fs.PathError"},
+//	  {"message": "no such file", "type": "syscall.Errno"}
+//	]}
+//
+// Logging "error", err only logs the error message. Causes are listed depth first, at most 32 are logged.
+// A nil error is logged as null.
+func ErrorAttr(err error) Attr {
+	if err == nil {
+		return slog.Any("error", nil)
+	}
+	return slog.Any("error", errorValue{err})
+}
+
+type errorValue struct {
+	err error
+}
+
+func (e errorValue) LogValue() slog.Value {
+	attrs := []Attr{
+		slog.String("message", e.err.Error()),
+		slog.String("type", fmt.Sprintf("%T", e.err)),
+	}
+	if causes := errorCauses(e.err); len(causes) > 0 {
+		attrs = append(attrs, slog.Any("causes", causes))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func errorCauses(err error) []map[string]string {
+	var causes []map[string]string
+	pending := unwrap(err)
+	for len(pending) > 0 && len(causes) < maxErrorCauses {
+		cause := pending[0]
+		pending = pending[1:]
+		if cause == nil {
+			continue
+		}
+		causes = append(causes, map[string]string{
+			"message": cause.Error(),
+			"type":    fmt.Sprintf("%T", cause),
+		})
+		pending = append(unwrap(cause), pending...)
+	}
+	return causes
+}
+
+func unwrap(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return []error{e.Unwrap()}
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	default:
+		return nil
+	}
+}
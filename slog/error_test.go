@@ -0,0 +1,88 @@
+package slog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/birdie-ai/golibs/slog"
+	"github.com/google/go-cmp/cmp"
+)
+
+type errorLog struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Causes  []struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"causes"`
+}
+
+func logError(t *testing.T, err error) *errorLog {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{}))
+	log.Error("failed", slog.ErrorAttr(err))
+
+	var record struct {
+		Error *errorLog `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("parsing log %q: %v", buf.String(), err)
+	}
+	return record.Error
+}
+
+func TestErrorAttr(t *testing.T) {
+	base := errors.New("base")
+	other := errors.New("other")
+	err := fmt.Errorf("wrapped: %w", errors.Join(fmt.Errorf("inner: %w", base), other))
+
+	got := logError(t, err)
+	if got == nil {
+		t.Fatal("got no error on log")
+	}
+	if got.Message != err.Error() {
+		t.Errorf("got message %q; want %q", got.Message, err.Error())
+	}
+	if got.Type != "*fmt.wrapError" {
+		t.Errorf("got type %q; want *fmt.wrapError", got.Type)
+	}
+
+	var gotCauses []string
+	for _, cause := range got.Causes {
+		gotCauses = append(gotCauses, cause.Message)
+	}
+	wantCauses := []string{errors.Join(fmt.Errorf("inner: %w", base), other).Error(), "inner: base", "base", "other"}
+	if diff := cmp.Diff(gotCauses, wantCauses); diff != "" {
+		t.Fatalf("causes diff: %v", diff)
+	}
+}
+
+func TestErrorAttrWithoutCauses(t *testing.T) {
+	got := logError(t, errors.New("error"))
+	if got == nil || got.Message != "error" || len(got.Causes) != 0 {
+		t.Fatalf("got %+v; want only message", got)
+	}
+}
+
+func TestErrorAttrNil(t *testing.T) {
+	if got := logError(t, nil); got != nil {
+		t.Fatalf("got %+v; want null error", got)
+	}
+}
+
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cyclic" }
+func (e *cyclicError) Unwrap() error { return e }
+
+func TestErrorAttrCycle(t *testing.T) {
+	got := logError(t, &cyclicError{})
+	if got == nil || len(got.Causes) != 32 {
+		t.Fatalf("got %+v; want causes limited to 32", got)
+	}
+}
@@ -10,7 +10,11 @@ import (
 	"log/slog"
 	"math"
 	"os"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/birdie-ai/golibs/internal/tracectx"
 )
 
 type (
@@ -24,6 +28,9 @@ type (
 	// Level determines the importance or severity of a log record
 	Level = slog.Level
 
+	// Attr is a key-value pair.
+	Attr = slog.Attr
+
 	// Logger represents a logger instance with its own context.
 	// It extends Go's slog.Logger by adding new methods, like [Logger.Fatal].
 	Logger struct {
@@ -49,6 +56,12 @@ const (
 	FormatGcloud = "gcloud"
 )
 
+// Special time formats, see [Config].
+const (
+	TimeFormatEpochMillis = "epoch_millis"
+	TimeFormatNone        = "none"
+)
+
 // Default configurations
 const (
 	DefaultLevel  = slog.LevelInfo
@@ -59,19 +72,73 @@ const (
 type Config struct {
 	Level  Level
 	Format Format
+	// TimeFormat is how the time of log records is formatted. If empty it is formatted as RFC 3339 (the default).
+	// Use [TimeFormatEpochMillis] for Unix epoch milliseconds (as a number) and [TimeFormatNone] to omit the time,
+	// like when the logging platform already adds it. Any other value is used as a layout for [time.Time.Format].
+	TimeFormat string
 }
 
 // Fatal is equivalent to [Logger.Error] followed by a call to os.Exit(1).
 func (l *Logger) Fatal(msg string, args ...any) {
-	l.Error(msg, args...)
+	logCaller(context.Background(), l.Logger, LevelError, msg, args)
 	os.Exit(1)
 }
 
+// LogCtx emits a log record with the given level and context, useful when the level is only known at runtime.
+// It is the same as Go's slog.Logger.Log, the source of the record is the caller of LogCtx.
+func (l *Logger) LogCtx(ctx context.Context, level Level, msg string, args ...any) {
+	logCaller(ctx, l.Logger, level, msg, args)
+}
+
+// logCaller logs a record with the caller of the function calling logCaller as the record PC (its source),
+// so functions of this package wrapping Go's slog are not reported as the source.
+func logCaller(ctx context.Context, l *slog.Logger, level Level, msg string, args []any) {
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip [runtime.Callers, logCaller, the wrapper function]
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
+}
+
 // With calls Logger.With on the default logger returning a new Logger instance.
 func (l *Logger) With(args ...any) *Logger {
 	return &Logger{l.Logger.With(args...)}
 }
 
+// AtLevel returns a clone of the logger that logs records with level >= the given level, ignoring the level of the
+// original logger handler. It is useful to change the verbosity of a specific scope, like logging debug records
+// only when handling specific requests with a logger that logs only info records. Use [NewContext] to
+// propagate the returned logger. The original logger is not affected.
+func (l *Logger) AtLevel(level Level) *Logger {
+	handler := l.Handler()
+	if lh, ok := handler.(*levelHandler); ok {
+		handler = lh.Handler
+	}
+	return &Logger{slog.New(&levelHandler{Handler: handler, level: level})}
+}
+
+// levelHandler overrides the level of the wrapped handler.
+type levelHandler struct {
+	slog.Handler
+	level Level
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level Level) bool {
+	return level >= h.level
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
 // LoadConfig will load the log Config of the service from environment variables.
 // The service name is used as a prefix for the environment variables.
 // So a service "TEST" will load the log level from "TEST_LOG_LEVEL".
@@ -106,8 +173,13 @@ func New(h Handler) *Logger {
 }
 
 // NewGoogleCloudHandler creates a [JSONHandler] that writes to w in a format that works well with Google Cloud Logging.
+// If opts has a ReplaceAttr function it is called before the attributes are customized for Google Cloud.
 func NewGoogleCloudHandler(w io.Writer, opts *slog.HandlerOptions) *slog.JSONHandler {
-	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+	gcloudOpts := *opts
+	gcloudOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if opts.ReplaceAttr != nil {
+			a = opts.ReplaceAttr(groups, a)
+		}
 		// Customize the name of some fields to match Google Cloud expectations
 		// More: https://cloud.google.com/logging/docs/agent/logging/configuration#process-payload
 		if len(groups) > 0 {
@@ -121,14 +193,15 @@ func NewGoogleCloudHandler(w io.Writer, opts *slog.HandlerOptions) *slog.JSONHan
 		}
 		return a
 	}
-	return slog.NewJSONHandler(w, opts)
+	return slog.NewJSONHandler(w, &gcloudOpts)
 }
 
 // Configure will change the default logger configuration.
 // It should be called as soon as possible, usually on the main of your program.
 func Configure(cfg Config) error {
 	opts := &slog.HandlerOptions{
-		Level: cfg.Level,
+		Level:       cfg.Level,
+		ReplaceAttr: replaceTime(cfg.TimeFormat),
 	}
 
 	var handler slog.Handler
@@ -147,29 +220,51 @@ func Configure(cfg Config) error {
 	return nil
 }
 
+// replaceTime returns a ReplaceAttr function that formats the time of log records as defined by [Config.TimeFormat].
+// It returns nil if the format is empty (the default).
+func replaceTime(format string) func([]string, slog.Attr) slog.Attr {
+	if format == "" {
+		return nil
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 || a.Key != slog.TimeKey || a.Value.Kind() != slog.KindTime {
+			return a
+		}
+		switch format {
+		case TimeFormatNone:
+			return slog.Attr{}
+		case TimeFormatEpochMillis:
+			a.Value = slog.Int64Value(a.Value.Time().UnixMilli())
+		default:
+			a.Value = slog.StringValue(a.Value.Time().Format(format))
+		}
+		return a
+	}
+}
+
 // Info calls Logger.Info on the default logger.
 func Info(msg string, args ...any) {
-	slog.Info(msg, args...)
+	logCaller(context.Background(), slog.Default(), LevelInfo, msg, args)
 }
 
 // Debug calls Logger.Debug on the default logger.
 func Debug(msg string, args ...any) {
-	slog.Debug(msg, args...)
+	logCaller(context.Background(), slog.Default(), LevelDebug, msg, args)
 }
 
 // Warn calls Logger.Warn on the default logger.
 func Warn(msg string, args ...any) {
-	slog.Warn(msg, args...)
+	logCaller(context.Background(), slog.Default(), LevelWarn, msg, args)
 }
 
 // Error calls Logger.Error on the default logger.
 func Error(msg string, args ...any) {
-	slog.Error(msg, args...)
+	logCaller(context.Background(), slog.Default(), LevelError, msg, args)
 }
 
 // Fatal is equivalent to Error() followed by a call to os.Exit(1).
 func Fatal(msg string, args ...any) {
-	Error(msg, args...)
+	logCaller(context.Background(), slog.Default(), LevelError, msg, args)
 	os.Exit(1)
 }
 
@@ -194,6 +289,29 @@ func FromCtx(ctx context.Context) *Logger {
 	return log
 }
 
+// WithContextFields returns the [Logger] associated with the given context (see [FromCtx]) with
+// the tracing information of the context added to it, like the ones added by the tracing package
+// (`trace_id`, `request_id`, `organization_id` and `user_id`).
+// Fields that are not present on the context are omitted.
+func WithContextFields(ctx context.Context) *Logger {
+	log := FromCtx(ctx)
+	fields := []struct {
+		name string
+		key  tracectx.Key
+	}{
+		{"trace_id", tracectx.TraceIDKey},
+		{"request_id", tracectx.RequestIDKey},
+		{"organization_id", tracectx.OrgIDKey},
+		{"user_id", tracectx.UserIDKey},
+	}
+	for _, field := range fields {
+		if v := tracectx.Get(ctx, field.key); v != "" {
+			log = log.With(field.name, v)
+		}
+	}
+	return log
+}
+
 // NewContext creates a new [context.Context] with the given [Logger] associated with it.
 // Call [FromCtx] to retrieve the [Logger].
 func NewContext(ctx context.Context, log *Logger) context.Context {
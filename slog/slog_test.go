@@ -1,11 +1,18 @@
 package slog_test
 
 import (
+	"bytes"
 	"context"
+	"io"
+	stdslog "log/slog"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/birdie-ai/golibs/slog"
+	"github.com/birdie-ai/golibs/tracing"
 )
 
 func ExampleNew() {
@@ -78,6 +85,143 @@ func TestContextIntegration(t *testing.T) {
 	}
 }
 
+func TestLoggerLogDynamicLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	ctx := context.Background()
+	log.LogCtx(ctx, slog.LevelInfo, "omitted")
+	if buf.Len() != 0 {
+		t.Fatalf("got unexpected log: %s", buf.String())
+	}
+
+	log.LogCtx(ctx, slog.LevelWarn, "warn msg", "key", "val")
+	if got := buf.String(); !strings.Contains(got, `"severity":"WARN"`) || !strings.Contains(got, "warn msg") {
+		t.Fatalf("got log %q; want warn msg", got)
+	}
+
+	buf.Reset()
+	// Go's slog.Logger.Log is still available
+	log.Log(ctx, slog.LevelError, "error msg")
+	if got := buf.String(); !strings.Contains(got, `"severity":"ERROR"`) || !strings.Contains(got, "error msg") {
+		t.Fatalf("got log %q; want error msg", got)
+	}
+}
+
+func TestLoggerAtLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	debugLog := log.With("key", "val").AtLevel(slog.LevelDebug)
+	debugLog.Debug("debug msg")
+	if got := buf.String(); !strings.Contains(got, "debug msg") || !strings.Contains(got, `"key":"val"`) {
+		t.Fatalf("got log %q; want debug msg with key", got)
+	}
+
+	buf.Reset()
+	log.Debug("omitted")
+	debugLog.AtLevel(slog.LevelError).Warn("omitted")
+	if buf.Len() != 0 {
+		t.Fatalf("got unexpected log: %s", buf.String())
+	}
+}
+
+func TestConfigureTimeFormat(t *testing.T) {
+	defaultLog := slog.Default()
+	defer stdslog.SetDefault(defaultLog.Logger)
+
+	cases := []struct {
+		format string
+		want   func(string) bool
+	}{
+		{"", func(log string) bool { return strings.Contains(log, `"time":"`) }},
+		{slog.TimeFormatNone, func(log string) bool { return !strings.Contains(log, `"time"`) }},
+		{slog.TimeFormatEpochMillis, func(log string) bool { return regexp.MustCompile(`"time":\d+,`).MatchString(log) }},
+		{time.DateOnly, func(log string) bool { return regexp.MustCompile(`"time":"\d{4}-\d{2}-\d{2}"`).MatchString(log) }},
+	}
+	for _, c := range cases {
+		got := captureStderr(t, func() {
+			err := slog.Configure(slog.Config{Level: slog.LevelInfo, Format: slog.FormatGcloud, TimeFormat: c.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slog.Info("msg")
+		})
+		if !c.want(got) || !strings.Contains(got, `"message":"msg"`) {
+			t.Errorf("time format %q: got unexpected log %q", c.format, got)
+		}
+	}
+}
+
+func TestGoogleCloudHandlerReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a stdslog.Attr) stdslog.Attr {
+			if a.Key == "secret" {
+				a.Value = stdslog.StringValue("REDACTED")
+			}
+			return a
+		},
+	}))
+	log.Info("msg", "secret", "value")
+
+	got := buf.String()
+	if !strings.Contains(got, `"secret":"REDACTED"`) || !strings.Contains(got, `"severity":"INFO"`) {
+		t.Fatalf("got log %q; want redacted secret with gcloud fields", got)
+	}
+}
+
+// captureStderr returns everything written to os.Stderr while f runs.
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(got)
+}
+
+func TestWithContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{}))
+
+	ctx := slog.NewContext(context.Background(), log)
+	ctx = tracing.CtxWithTraceID(ctx, "trace-id")
+	ctx = tracing.CtxWithRequestID(ctx, "request-id")
+
+	slog.WithContextFields(ctx).Info("msg")
+
+	got := buf.String()
+	for _, want := range []string{`"trace_id":"trace-id"`, `"request_id":"request-id"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got log %q; want %q", got, want)
+		}
+	}
+	for _, absent := range []string{"organization_id", "user_id"} {
+		if strings.Contains(got, absent) {
+			t.Errorf("got log %q; want no %q", got, absent)
+		}
+	}
+}
+
 func TestDefaultLoggerFromContext(t *testing.T) {
 	got := slog.FromCtx(context.Background())
 	if got == nil {
@@ -0,0 +1,60 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// NewSourceOnLevelHandler creates a [Handler] that adds the source location (file:line) of the
+// log call as a `source` attribute, but only to records with level >= minLevel, like adding source
+// only to errors. Go's [HandlerOptions] AddSource is all or nothing and can be expensive.
+// All records are handled by the given inner handler (which should not have AddSource enabled).
+//
+// The source is resolved from the record PC, like Go's slog does, so it is the caller of the logger
+// (including this package wrappers, like [Info] or [Logger.LogCtx]). Since the attribute is added to the record, if a group was opened
+// with [Handler.WithGroup] the source will be inside the group.
+func NewSourceOnLevelHandler(inner Handler, minLevel Level) Handler {
+	return &sourceOnLevelHandler{inner: inner, minLevel: minLevel}
+}
+
+type sourceOnLevelHandler struct {
+	inner    Handler
+	minLevel Level
+}
+
+func (h *sourceOnLevelHandler) Enabled(ctx context.Context, level Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *sourceOnLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.minLevel {
+		if source, ok := recordSource(r); ok {
+			r = r.Clone()
+			r.AddAttrs(slog.Any(slog.SourceKey, source))
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *sourceOnLevelHandler) WithAttrs(attrs []slog.Attr) Handler {
+	return &sourceOnLevelHandler{inner: h.inner.WithAttrs(attrs), minLevel: h.minLevel}
+}
+
+func (h *sourceOnLevelHandler) WithGroup(name string) Handler {
+	return &sourceOnLevelHandler{inner: h.inner.WithGroup(name), minLevel: h.minLevel}
+}
+
+// recordSource resolves the source of the record from its PC, which is the caller of the logger.
+func recordSource(r slog.Record) (*slog.Source, bool) {
+	if r.PC == 0 {
+		return nil, false
+	}
+	frames := runtime.CallersFrames([]uintptr{r.PC})
+	frame, _ := frames.Next()
+	return &slog.Source{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+	}, true
+}
@@ -0,0 +1,70 @@
+package slog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdslog "log/slog"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+func TestSourceOnLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewSourceOnLevelHandler(slog.NewGoogleCloudHandler(&buf, &slog.HandlerOptions{}), slog.LevelWarn))
+
+	type record struct {
+		Message string `json:"message"`
+		Source  *struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"source"`
+	}
+	parse := func() record {
+		t.Helper()
+		var r record
+		if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+			t.Fatalf("parsing log %q: %v", buf.String(), err)
+		}
+		buf.Reset()
+		return r
+	}
+
+	log.Info("info")
+	if r := parse(); r.Source != nil {
+		t.Fatalf("got source %+v on info log; want none", r.Source)
+	}
+
+	_, wantFile, line, _ := runtime.Caller(0)
+	log.Warn("warn")
+	assertSource := func(r record, wantLine int) {
+		t.Helper()
+		if r.Source == nil {
+			t.Fatalf("got no source on log %q", r.Message)
+		}
+		if r.Source.File != wantFile || r.Source.Line != wantLine {
+			t.Fatalf("got source %s:%d; want %s:%d", r.Source.File, r.Source.Line, wantFile, wantLine)
+		}
+		if !strings.HasSuffix(r.Source.Function, "TestSourceOnLevelHandler") {
+			t.Fatalf("got source function %q", r.Source.Function)
+		}
+	}
+	assertSource(parse(), line+1)
+
+	// Wrapper methods of our logger must be skipped
+	_, _, line, _ = runtime.Caller(0)
+	log.With("a", "b").LogCtx(context.Background(), slog.LevelError, "error")
+	assertSource(parse(), line+1)
+
+	defaultLog := slog.Default()
+	defer stdslog.SetDefault(defaultLog.Logger)
+	stdslog.SetDefault(log.Logger)
+
+	_, _, line, _ = runtime.Caller(0)
+	slog.Error("error")
+	assertSource(parse(), line+1)
+}
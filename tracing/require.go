@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+type (
+	// RequireOrgIDOption is used to configure handlers created with [RequireOrgID].
+	RequireOrgIDOption func(*requireOrgIDConfig)
+
+	requireOrgIDConfig struct {
+		statusCode int
+		body       []byte
+	}
+)
+
+// RequireOrgID creates a [http.Handler] that rejects requests without an organization ID on their context
+// (see [CtxGetOrgID]), requests with an organization ID are passed to the given handler.
+// It is intended to wrap handlers already instrumented with [InstrumentHTTP], like InstrumentHTTP(RequireOrgID(h)),
+// so the organization ID is populated from the request headers.
+//
+// By default rejected requests get a 400 (Bad Request) response and are logged.
+// The response can be configured with [RequireOrgIDWithStatus] and [RequireOrgIDWithBody].
+func RequireOrgID(next http.Handler, options ...RequireOrgIDOption) http.Handler {
+	cfg := requireOrgIDConfig{
+		statusCode: http.StatusBadRequest,
+		body:       []byte("missing organization ID\n"),
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if CtxGetOrgID(ctx) != "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		log := slog.FromCtx(ctx)
+		log.Warn("tracing: rejecting request without organization ID", "method", req.Method, "path", req.URL.Path)
+
+		res.WriteHeader(cfg.statusCode)
+		if _, err := res.Write(cfg.body); err != nil {
+			log.Debug("tracing: writing missing organization ID response", "error", err)
+		}
+	})
+}
+
+// RequireOrgIDWithStatus configures the status code of the response sent by [RequireOrgID] on rejected requests.
+func RequireOrgIDWithStatus(statusCode int) RequireOrgIDOption {
+	return func(cfg *requireOrgIDConfig) {
+		cfg.statusCode = statusCode
+	}
+}
+
+// RequireOrgIDWithBody configures the body of the response sent by [RequireOrgID] on rejected requests.
+func RequireOrgIDWithBody(body []byte) RequireOrgIDOption {
+	return func(cfg *requireOrgIDConfig) {
+		cfg.body = body
+	}
+}
@@ -0,0 +1,59 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/birdie-ai/golibs/tracing"
+)
+
+func TestRequireOrgID(t *testing.T) {
+	const wantOrgID = "orgid"
+
+	var gotOrgID string
+	handler := tracing.InstrumentHTTP(tracing.RequireOrgID(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		gotOrgID = tracing.CtxGetOrgID(req.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Birdie-Organization-ID", wantOrgID)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", res.Code, http.StatusOK)
+	}
+	if gotOrgID != wantOrgID {
+		t.Fatalf("got org ID %q; want %q", gotOrgID, wantOrgID)
+	}
+
+	gotOrgID = ""
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d; want %d", res.Code, http.StatusBadRequest)
+	}
+	if gotOrgID != "" {
+		t.Fatal("handler called for request without org ID")
+	}
+}
+
+func TestRequireOrgIDWithOptions(t *testing.T) {
+	const wantBody = `{"error":"missing org"}`
+
+	handler := tracing.RequireOrgID(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("handler called for request without org ID")
+	}), tracing.RequireOrgIDWithStatus(http.StatusForbidden), tracing.RequireOrgIDWithBody([]byte(wantBody)))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("got status %d; want %d", res.Code, http.StatusForbidden)
+	}
+	if got := res.Body.String(); got != wantBody {
+		t.Fatalf("got body %q; want %q", got, wantBody)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/birdie-ai/golibs/internal/tracectx"
 	"github.com/birdie-ai/golibs/slog"
 	"github.com/google/uuid"
 )
@@ -26,22 +27,48 @@ type RequestStats struct {
 // StatsHandler handles completed requests stats (like logging).
 type StatsHandler func(context.Context, RequestStats)
 
+// InstrumentOption configures handlers created with [InstrumentHTTP] and [InstrumentHTTPWithStats].
+type InstrumentOption func(*instrumentConfig)
+
+type instrumentConfig struct {
+	requestIDHeader string
+}
+
+// InstrumentWithRequestIDHeader configures the name of the header used to get the request ID of incoming requests.
+// If the header is present its value is used as the request ID instead of generating a new one, like when
+// replaying a request, so logs are correlated with the original request. Defaults to "Birdie-Request-ID".
+// An empty name disables this, always generating new request IDs.
+func InstrumentWithRequestIDHeader(name string) InstrumentOption {
+	return func(cfg *instrumentConfig) {
+		cfg.requestIDHeader = name
+	}
+}
+
 // InstrumentHTTP will instrument the given [http.handler] by adding a slog.Logger on the request context.
-// The logger will have `trace_id`, `request_id` and `organization_id` added to it.
+// The logger will have `trace_id`, `request_id`, `organization_id` and `user_id` added to it.
 // Use slog.FromCtx(ctx) to retrieve the logger.
 // It will log each completed request on the INFO level (may be too much for some services, for more fine grained control see [InstrumentHTTPWithStats]).
-func InstrumentHTTP(h http.Handler) http.Handler {
+// The request ID is taken from the request headers when present, see [InstrumentWithRequestIDHeader].
+func InstrumentHTTP(h http.Handler, options ...InstrumentOption) http.Handler {
 	return InstrumentHTTPWithStats(h, func(ctx context.Context, req RequestStats) {
 		// More: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
 		slog.FromCtx(ctx).Info("handled request", "httpRequest", req)
-	})
+	}, options...)
 }
 
 // InstrumentHTTPWithStats will instrument the given [http.handler] by adding a slog.Logger on the request context.
-// The logger will have `trace_id`, `request_id` and `organization_id` added to it.
+// The logger will have `trace_id`, `request_id`, `organization_id` and `user_id` added to it.
 // Use slog.FromCtx(ctx) to retrieve the logger.
 // For each completed request the provided [StatsHandler] will be called.
-func InstrumentHTTPWithStats(h http.Handler, statsHandler StatsHandler) http.Handler {
+// The request ID is taken from the request headers when present, see [InstrumentWithRequestIDHeader].
+func InstrumentHTTPWithStats(h http.Handler, statsHandler StatsHandler, options ...InstrumentOption) http.Handler {
+	cfg := instrumentConfig{
+		requestIDHeader: requestIDHeader,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		// We don't parse/generate trace IDs exactly as in the spec, for now
 		// just using the specified header name.
@@ -50,20 +77,25 @@ func InstrumentHTTPWithStats(h http.Handler, statsHandler StatsHandler) http.Han
 			traceID = uuid.NewString()
 		}
 		orgID := req.Header.Get(orgIDHeader)
+		userID := req.Header.Get(userIDHeader)
 
 		ctx := req.Context()
 		ctx = CtxWithTraceID(ctx, traceID)
 		if orgID != "" {
 			ctx = CtxWithOrgID(ctx, orgID)
 		}
-
-		log := slog.FromCtx(ctx)
-		log = log.With("trace_id", traceID)
-		log = log.With("request_id", uuid.NewString())
-		if orgID != "" {
-			log = log.With("organization_id", orgID)
+		if userID != "" {
+			ctx = CtxWithUserID(ctx, userID)
 		}
-		ctx = slog.NewContext(ctx, log)
+		var requestID string
+		if cfg.requestIDHeader != "" {
+			requestID = req.Header.Get(cfg.requestIDHeader)
+		}
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		ctx = CtxWithRequestID(ctx, requestID)
+		ctx = slog.NewContext(ctx, slog.WithContextFields(ctx))
 
 		httpReq := RequestStats{
 			Method:      req.Method,
@@ -95,24 +127,46 @@ func InstrumentHTTPWithStats(h http.Handler, statsHandler StatsHandler) http.Han
 // CtxWithTraceID creates a new [context.Context] with the given trace ID associated with it.
 // Call [CtxGetTraceID] to retrieve the trace ID.
 func CtxWithTraceID(ctx context.Context, traceID string) context.Context {
-	return context.WithValue(ctx, traceIDKey, traceID)
+	return tracectx.With(ctx, tracectx.TraceIDKey, traceID)
 }
 
 // CtxGetTraceID gets the trace ID associated with this context.
 // Return the trace ID and true if there is a trace ID, empty and false otherwise.
 func CtxGetTraceID(ctx context.Context) string {
-	return ctxget(ctx, traceIDKey)
+	return tracectx.Get(ctx, tracectx.TraceIDKey)
 }
 
 // CtxWithOrgID creates a new [context.Context] with the given organization ID associated with it.
 // Call [CtxGetOrgID] to retrieve the organization ID.
 func CtxWithOrgID(ctx context.Context, orgID string) context.Context {
-	return context.WithValue(ctx, orgIDKey, orgID)
+	return tracectx.With(ctx, tracectx.OrgIDKey, orgID)
 }
 
 // CtxGetOrgID gets the trace ID associated with this context.
 func CtxGetOrgID(ctx context.Context) string {
-	return ctxget(ctx, orgIDKey)
+	return tracectx.Get(ctx, tracectx.OrgIDKey)
+}
+
+// CtxWithUserID creates a new [context.Context] with the given user ID associated with it.
+// Call [CtxGetUserID] to retrieve the user ID.
+func CtxWithUserID(ctx context.Context, userID string) context.Context {
+	return tracectx.With(ctx, tracectx.UserIDKey, userID)
+}
+
+// CtxGetUserID gets the user ID associated with this context.
+func CtxGetUserID(ctx context.Context) string {
+	return tracectx.Get(ctx, tracectx.UserIDKey)
+}
+
+// CtxWithRequestID creates a new [context.Context] with the given request ID associated with it.
+// Call [CtxGetRequestID] to retrieve the request ID.
+func CtxWithRequestID(ctx context.Context, requestID string) context.Context {
+	return tracectx.With(ctx, tracectx.RequestIDKey, requestID)
+}
+
+// CtxGetRequestID gets the request ID associated with this context.
+func CtxGetRequestID(ctx context.Context) string {
+	return tracectx.Get(ctx, tracectx.RequestIDKey)
 }
 
 // SetRequestHeaders adds headers to the given [Request] using information
@@ -127,6 +181,9 @@ func SetRequestHeaders(ctx context.Context, req *http.Request) {
 	if orgID := CtxGetOrgID(ctx); orgID != "" {
 		req.Header.Set(orgIDHeader, orgID)
 	}
+	if userID := CtxGetUserID(ctx); userID != "" {
+		req.Header.Set(userIDHeader, userID)
+	}
 }
 
 type (
@@ -144,16 +201,14 @@ type (
 		*responseWriter
 		http.Flusher
 	}
-
-	// key is the type used to store data on contexts.
-	key int
 )
 
 const (
-	traceIDHeader     = "traceparent"
-	orgIDHeader       = "Birdie-Organization-ID"
-	traceIDKey    key = iota
-	orgIDKey
+	traceIDHeader = "traceparent"
+	orgIDHeader   = "Birdie-Organization-ID"
+	userIDHeader  = "Birdie-User-ID"
+
+	requestIDHeader = "Birdie-Request-ID"
 )
 
 func newResponseWriter(r http.ResponseWriter) responseWriterObserver {
@@ -186,15 +241,3 @@ func (r *responseWriter) Write(b []byte) (int, error) {
 	r.contentLength += n
 	return n, err
 }
-
-func ctxget(ctx context.Context, k key) string {
-	val := ctx.Value(k)
-	if val == nil {
-		return ""
-	}
-	str, ok := val.(string)
-	if !ok {
-		return ""
-	}
-	return str
-}
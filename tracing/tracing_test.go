@@ -23,14 +23,17 @@ func TestSetRequestHeaders(t *testing.T) {
 	const (
 		wantTraceID = "traceid"
 		wantOrgID   = "orgid"
+		wantUserID  = "userid"
 	)
 
 	ctx = tracing.CtxWithOrgID(ctx, wantOrgID)
 	ctx = tracing.CtxWithTraceID(ctx, wantTraceID)
+	ctx = tracing.CtxWithUserID(ctx, wantUserID)
 
 	tracing.SetRequestHeaders(ctx, req)
 	gotTraceID := req.Header.Get("traceparent")
 	gotOrgID := req.Header.Get("Birdie-Organization-ID")
+	gotUserID := req.Header.Get("Birdie-User-ID")
 
 	if gotTraceID != wantTraceID {
 		t.Fatalf("got traceID %q; want %q", gotTraceID, wantTraceID)
@@ -38,6 +41,9 @@ func TestSetRequestHeaders(t *testing.T) {
 	if gotOrgID != wantOrgID {
 		t.Fatalf("got orgID %q; want %q", gotOrgID, wantOrgID)
 	}
+	if gotUserID != wantUserID {
+		t.Fatalf("got userID %q; want %q", gotUserID, wantUserID)
+	}
 }
 
 func TestSetRequestHeadersEmptyCtx(t *testing.T) {
@@ -57,6 +63,7 @@ func TestIntrumentedHTTPHandler(t *testing.T) {
 	const (
 		wantTraceID = "test-trace-id"
 		wantOrgID   = "test-org-id"
+		wantUserID  = "test-user-id"
 		wantStatus  = 201 // should be a non-default status, to actually test things.
 		wantBody    = "Worked!"
 	)
@@ -64,12 +71,16 @@ func TestIntrumentedHTTPHandler(t *testing.T) {
 		gotLogger         *slog.Logger
 		gotTraceID        string
 		gotOrgID          string
+		gotUserID         string
+		gotRequestID      string
 		gotResponseWriter http.ResponseWriter
 	)
 	handler := tracing.InstrumentHTTP(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		gotLogger = slog.FromCtx(req.Context())
 		gotTraceID = tracing.CtxGetTraceID(req.Context())
 		gotOrgID = tracing.CtxGetOrgID(req.Context())
+		gotUserID = tracing.CtxGetUserID(req.Context())
+		gotRequestID = tracing.CtxGetRequestID(req.Context())
 		w.WriteHeader(wantStatus)
 		_, _ = fmt.Fprint(w, wantBody)
 		gotResponseWriter = w
@@ -78,6 +89,7 @@ func TestIntrumentedHTTPHandler(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Set("traceparent", wantTraceID)
 	req.Header.Set("Birdie-Organization-ID", wantOrgID)
+	req.Header.Set("Birdie-User-ID", wantUserID)
 	w := httptest.NewRecorder()
 
 	handler.ServeHTTP(w, req)
@@ -91,6 +103,12 @@ func TestIntrumentedHTTPHandler(t *testing.T) {
 	if gotOrgID != wantOrgID {
 		t.Fatalf("got %q != want %q", gotOrgID, wantOrgID)
 	}
+	if gotUserID != wantUserID {
+		t.Fatalf("got %q != want %q", gotUserID, wantUserID)
+	}
+	if gotRequestID == "" {
+		t.Fatal("got empty request ID")
+	}
 	res := w.Result()
 	if got := res.StatusCode; got != wantStatus {
 		t.Fatalf("got status %v; want %v", got, wantStatus)
@@ -105,6 +123,46 @@ func TestIntrumentedHTTPHandler(t *testing.T) {
 	}
 }
 
+func TestIntrumentedHTTPHandlerRequestID(t *testing.T) {
+	const wantRequestID = "original-request-id"
+
+	var gotRequestID string
+	handler := http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		gotRequestID = tracing.CtxGetRequestID(req.Context())
+	})
+
+	serve := func(h http.Handler, header, requestID string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if requestID != "" {
+			req.Header.Set(header, requestID)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(tracing.InstrumentHTTP(handler), "Birdie-Request-ID", wantRequestID)
+	if gotRequestID != wantRequestID {
+		t.Fatalf("got request ID %q; want %q", gotRequestID, wantRequestID)
+	}
+
+	serve(tracing.InstrumentHTTP(handler), "Birdie-Request-ID", "")
+	if gotRequestID == "" || gotRequestID == wantRequestID {
+		t.Fatalf("got request ID %q; want a new one", gotRequestID)
+	}
+
+	custom := tracing.InstrumentHTTP(handler, tracing.InstrumentWithRequestIDHeader("X-Request-ID"))
+	serve(custom, "X-Request-ID", wantRequestID)
+	if gotRequestID != wantRequestID {
+		t.Fatalf("got request ID %q; want %q", gotRequestID, wantRequestID)
+	}
+
+	disabled := tracing.InstrumentHTTP(handler, tracing.InstrumentWithRequestIDHeader(""))
+	serve(disabled, "Birdie-Request-ID", wantRequestID)
+	if gotRequestID == "" || gotRequestID == wantRequestID {
+		t.Fatalf("got request ID %q; want a new one", gotRequestID)
+	}
+}
+
 func TestIntrumentedHTTPHandlerNoFlusher(t *testing.T) {
 	const (
 		wantTraceID = "test-trace-id"
@@ -192,3 +250,31 @@ func TestCtxWithTraceAndOrgID(t *testing.T) {
 		t.Fatalf("got %q != want %q", got, wantTraceID)
 	}
 }
+
+func TestCtxWithUserID(t *testing.T) {
+	const wantUserID = "user-id-value"
+
+	ctx := context.Background()
+	if got := tracing.CtxGetUserID(ctx); got != "" {
+		t.Fatalf("unexpected user id: %q", got)
+	}
+
+	ctx = tracing.CtxWithUserID(ctx, wantUserID)
+	if got := tracing.CtxGetUserID(ctx); got != wantUserID {
+		t.Fatalf("got %q != want %q", got, wantUserID)
+	}
+}
+
+func TestCtxWithRequestID(t *testing.T) {
+	const wantRequestID = "request-id-value"
+
+	ctx := context.Background()
+	if got := tracing.CtxGetRequestID(ctx); got != "" {
+		t.Fatalf("unexpected request id: %q", got)
+	}
+
+	ctx = tracing.CtxWithRequestID(ctx, wantRequestID)
+	if got := tracing.CtxGetRequestID(ctx); got != wantRequestID {
+		t.Fatalf("got %q != want %q", got, wantRequestID)
+	}
+}
@@ -0,0 +1,167 @@
+package xhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// BreakerOptions configures circuit breaker clients created with [NewCircuitBreakerClient].
+	// Zero values are replaced by their defaults.
+	BreakerOptions struct {
+		// FailureThreshold is how many failures within [BreakerOptions.Window] open the circuit.
+		// Defaults to [DefaultBreakerFailureThreshold].
+		FailureThreshold int
+		// Window is the period where failures are counted. Defaults to [DefaultBreakerWindow].
+		Window time.Duration
+		// OpenPeriod is how long the circuit stays open (failing fast) before a probe request is allowed.
+		// Defaults to [DefaultBreakerOpenPeriod].
+		OpenPeriod time.Duration
+		// IsFailure decides if the result of a request is a failure.
+		// Defaults to requests with errors or 5xx status codes.
+		IsFailure func(*http.Response, error) bool
+	}
+
+	breakerState int
+
+	circuitBreakerClient struct {
+		client   Client
+		opts     BreakerOptions
+		mutex    sync.Mutex
+		breakers map[string]*breaker
+	}
+
+	breaker struct {
+		state       breakerState
+		windowStart time.Time
+		failures    int
+		openedAt    time.Time
+	}
+)
+
+// ErrCircuitOpen is returned by circuit breaker clients (see [NewCircuitBreakerClient]) when the circuit
+// for a host is open and the request was not sent.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Default circuit breaker configurations, see [BreakerOptions].
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerWindow           = 10 * time.Second
+	DefaultBreakerOpenPeriod       = 30 * time.Second
+)
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// NewCircuitBreakerClient wraps the given client with a circuit breaker, tracked per host (the request URL host).
+// When [BreakerOptions.FailureThreshold] failures happen within [BreakerOptions.Window] the circuit opens and
+// requests fail fast with [ErrCircuitOpen], without calling the wrapped client.
+// After [BreakerOptions.OpenPeriod] the circuit half-opens, allowing a single probe request: if it succeeds the circuit
+// closes, otherwise it opens again. Other requests fail with [ErrCircuitOpen] while the probe is in progress, and the
+// results of requests sent before the circuit opened are ignored.
+//
+// The returned client is safe to use concurrently. Errors matching ErrCircuitOpen are not retried by [NewRetrierClient],
+// so NewRetrierClient(NewCircuitBreakerClient(c, opts)) retries transient failures until the circuit opens.
+func NewCircuitBreakerClient(c Client, opts BreakerOptions) Client {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultBreakerWindow
+	}
+	if opts.OpenPeriod <= 0 {
+		opts.OpenPeriod = DefaultBreakerOpenPeriod
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = defaultIsFailure
+	}
+	return &circuitBreakerClient{
+		client:   c,
+		opts:     opts,
+		breakers: map[string]*breaker{},
+	}
+}
+
+func (c *circuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	allowed, probe := c.allow(host)
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	}
+	res, err := c.client.Do(req)
+	c.done(host, probe, c.opts.IsFailure(res, err))
+	return res, err
+}
+
+// allow checks if a request to the given host can be sent, moving open circuits to half-open when possible.
+// When the circuit half-opens the allowed request is the probe, the only one whose result can close or open the circuit.
+func (c *circuitBreakerClient) allow(host string) (allowed, probe bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{}
+		c.breakers[host] = b
+	}
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < c.opts.OpenPeriod {
+			return false, false
+		}
+		b.state = breakerHalfOpen
+		return true, true
+	case breakerHalfOpen:
+		// Only the probe request is allowed
+		return false, false
+	default:
+		return true, false
+	}
+}
+
+// done records the result of a request to the given host.
+func (c *circuitBreakerClient) done(host string, probe, failed bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b := c.breakers[host]
+	now := time.Now()
+
+	if b.state == breakerHalfOpen {
+		// Requests sent before the circuit opened may finish while probing, only the probe result counts
+		if !probe {
+			return
+		}
+		if failed {
+			b.state = breakerOpen
+			b.openedAt = now
+			return
+		}
+		*b = breaker{}
+		return
+	}
+
+	if !failed || b.state == breakerOpen {
+		return
+	}
+	if now.Sub(b.windowStart) > c.opts.Window {
+		b.windowStart = now
+		b.failures = 0
+	}
+	b.failures++
+	if b.failures >= c.opts.FailureThreshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+func defaultIsFailure(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= 500
+}
@@ -0,0 +1,188 @@
+package xhttp_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestCircuitBreakerClient(t *testing.T) {
+	const openPeriod = 50 * time.Millisecond
+
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewCircuitBreakerClient(fakeClient, xhttp.BreakerOptions{
+		FailureThreshold: 2,
+		Window:           time.Minute,
+		OpenPeriod:       openPeriod,
+	})
+
+	do := func(url string) error {
+		t.Helper()
+		_, err := client.Do(newRequest(t, http.MethodGet, url, nil))
+		return err
+	}
+	assertRequests := func(want int) {
+		t.Helper()
+		assertEqual(t, len(fakeClient.Requests()), want)
+	}
+
+	fakeClient.PushError(errors.New("failed"))
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusInternalServerError})
+	if err := do("http://a"); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if err := do("http://a"); err != nil {
+		t.Fatal(err)
+	}
+	assertRequests(2)
+
+	// Circuit is open for host a only
+	if err := do("http://a/path"); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Fatalf("got err %v; want %v", err, xhttp.ErrCircuitOpen)
+	}
+	assertRequests(2)
+
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK})
+	if err := do("http://b"); err != nil {
+		t.Fatal(err)
+	}
+	assertRequests(3)
+
+	// Failed probe opens the circuit again
+	time.Sleep(openPeriod)
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusServiceUnavailable})
+	if err := do("http://a"); err != nil {
+		t.Fatal(err)
+	}
+	assertRequests(4)
+	if err := do("http://a"); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Fatalf("got err %v; want %v", err, xhttp.ErrCircuitOpen)
+	}
+
+	// Successful probe closes the circuit
+	time.Sleep(openPeriod)
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK})
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK})
+	for range 2 {
+		if err := do("http://a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertRequests(6)
+}
+
+func TestCircuitBreakerClientHalfOpenAllowsSingleProbe(t *testing.T) {
+	const openPeriod = 10 * time.Millisecond
+
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewCircuitBreakerClient(fakeClient, xhttp.BreakerOptions{
+		FailureThreshold: 1,
+		OpenPeriod:       openPeriod,
+	})
+
+	fakeClient.PushError(errors.New("failed"))
+	if _, err := client.Do(newRequest(t, http.MethodGet, "http://a", nil)); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	time.Sleep(openPeriod)
+
+	probing := make(chan struct{})
+	probeDone := make(chan struct{})
+	release := make(chan struct{})
+	fakeClient.OnDo(func(*http.Request) {
+		close(probing)
+		<-release
+	})
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK})
+
+	go func() {
+		defer close(probeDone)
+		if _, err := client.Do(newRequest(t, http.MethodGet, "http://a", nil)); err != nil {
+			t.Errorf("probe failed: %v", err)
+		}
+	}()
+
+	<-probing
+	if _, err := client.Do(newRequest(t, http.MethodGet, "http://a", nil)); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Errorf("got err %v while probing; want %v", err, xhttp.ErrCircuitOpen)
+	}
+	close(release)
+	<-probeDone
+}
+
+func TestCircuitBreakerClientHalfOpenIgnoresRequestsSentBeforeOpening(t *testing.T) {
+	const openPeriod = 10 * time.Millisecond
+
+	slowSent := make(chan struct{})
+	slowRelease := make(chan struct{})
+	probing := make(chan struct{})
+	probeRelease := make(chan struct{})
+	client := xhttp.NewCircuitBreakerClient(clientFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/slow":
+			close(slowSent)
+			<-slowRelease
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		case "/probe":
+			close(probing)
+			<-probeRelease
+			return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+		}
+		return nil, errors.New("failed")
+	}), xhttp.BreakerOptions{
+		FailureThreshold: 1,
+		OpenPeriod:       openPeriod,
+	})
+	do := func(path string) error {
+		t.Helper()
+		_, err := client.Do(newRequest(t, http.MethodGet, "http://a"+path, nil))
+		return err
+	}
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		if err := do("/slow"); err != nil {
+			t.Errorf("slow request failed: %v", err)
+		}
+	}()
+	<-slowSent
+
+	if err := do("/fail"); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	time.Sleep(openPeriod)
+
+	probeDone := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		if err := do("/probe"); err != nil {
+			t.Errorf("probe failed: %v", err)
+		}
+	}()
+	<-probing
+
+	// The slow request succeeds while probing, but it was sent before the circuit opened so it doesn't close it
+	close(slowRelease)
+	<-slowDone
+	if err := do("/other"); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Errorf("got err %v while probing; want %v", err, xhttp.ErrCircuitOpen)
+	}
+
+	// The failed probe opens the circuit again
+	close(probeRelease)
+	<-probeDone
+	if err := do("/other"); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Errorf("got err %v after failed probe; want %v", err, xhttp.ErrCircuitOpen)
+	}
+}
+
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
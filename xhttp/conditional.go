@@ -0,0 +1,89 @@
+package xhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// Response is a response with a parsed body, returned by functions like [GetConditional].
+type Response[T any] struct {
+	// Body is the parsed response body.
+	Body T
+	// ETag is the ETag header of the response, used to send conditional requests for the same resource.
+	ETag string
+	// Header has all the response headers.
+	Header http.Header
+	// Elapsed is how long the request took, including reading and parsing the response body.
+	Elapsed time.Duration
+	// TTFB (time to first byte) is how long it took to receive the first byte of the response, since the request started.
+	// Compared with Elapsed it distinguishes the server processing time from the response transfer time.
+	// It is zero if the client doesn't support tracing with [httptrace.ClientTrace] (only [http.Client] does).
+	TTFB time.Duration
+}
+
+// GetConditional sends a conditional GET request (created with [NewRequestWithContext]) for the given url using the given client,
+// setting the If-None-Match header with the given etag (if not empty), and parses the JSON response body as [T].
+// It is useful for polling resources that rarely change without downloading them again when they didn't change.
+//
+// It returns true if the resource changed, with the parsed body and the new ETag on the response.
+// If the server answers with 304 (Not Modified) it returns false and the response has a zero value body and the same
+// ETag (the new one, if the server sent one, or the given one). Any other non 2xx status code is an error.
+func GetConditional[T any](ctx context.Context, c Client, url, etag string) (*Response[T], bool, error) {
+	req, err := NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("xhttp.GetConditional: creating request: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	start := time.Now()
+	req, ttfb := traceTTFB(req, start)
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("xhttp.GetConditional: sending request: %w", err)
+	}
+	defer closeBody(ctx, res.Body)
+
+	response := &Response[T]{
+		ETag:   res.Header.Get("ETag"),
+		Header: res.Header,
+	}
+	if res.StatusCode == http.StatusNotModified {
+		if response.ETag == "" {
+			response.ETag = etag
+		}
+		response.Elapsed = time.Since(start)
+		response.TTFB = time.Duration(ttfb.Load())
+		return response, false, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// Drain (part of) the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainSize))
+		return nil, false, fmt.Errorf("xhttp.GetConditional: unexpected status %d", res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response.Body); err != nil {
+		return nil, false, fmt.Errorf("xhttp.GetConditional: parsing response body: %w", err)
+	}
+	response.Elapsed = time.Since(start)
+	response.TTFB = time.Duration(ttfb.Load())
+	return response, true, nil
+}
+
+// traceTTFB returns a request that measures the time to first byte since start, which is available after the
+// request is done. If the request is retried the last response is measured.
+func traceTTFB(req *http.Request, start time.Time) (*http.Request, *atomic.Int64) {
+	var ttfb atomic.Int64
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			ttfb.Store(int64(time.Since(start)))
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), &ttfb
+}
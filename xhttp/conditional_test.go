@@ -0,0 +1,113 @@
+package xhttp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+type resource struct {
+	Name string `json:"name"`
+}
+
+func TestGetConditional(t *testing.T) {
+	ctx := context.Background()
+	fakeClient := xhttptest.NewClient()
+
+	body := watchClose(strings.NewReader(`{"name":"v1"}`))
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": []string{`"v1"`}},
+		Body:       body,
+	})
+
+	res, changed, err := xhttp.GetConditional[resource](ctx, fakeClient, "http://test/resource", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, changed, true)
+	assertEqual(t, res.Body, resource{Name: "v1"})
+	assertEqual(t, res.ETag, `"v1"`)
+	assertEqual(t, body.CloseCalls, 1)
+
+	body = watchClose(strings.NewReader(""))
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusNotModified,
+		Header:     http.Header{},
+		Body:       body,
+	})
+
+	res, changed, err = xhttp.GetConditional[resource](ctx, fakeClient, "http://test/resource", res.ETag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, changed, false)
+	assertEqual(t, res.Body, resource{})
+	assertEqual(t, res.ETag, `"v1"`)
+	assertEqual(t, body.CloseCalls, 1)
+
+	requests := fakeClient.Requests()
+	assertEqual(t, len(requests), 2)
+	assertEqual(t, requests[0].Header.Get("If-None-Match"), "")
+	assertEqual(t, requests[1].Header.Get("If-None-Match"), `"v1"`)
+	assertEqual(t, requests[1].Method, http.MethodGet)
+	assertEqual(t, requests[1].URL.String(), "http://test/resource")
+}
+
+func TestGetConditionalErrors(t *testing.T) {
+	ctx := context.Background()
+	cases := []*http.Response{
+		{StatusCode: http.StatusNotFound, Body: watchClose(strings.NewReader("not found"))},
+		{StatusCode: http.StatusOK, Body: watchClose(strings.NewReader("not json"))},
+	}
+	for _, c := range cases {
+		fakeClient := xhttptest.NewClient()
+		fakeClient.PushResponse(c)
+
+		if _, _, err := xhttp.GetConditional[resource](ctx, fakeClient, "http://test/resource", `"v1"`); err == nil {
+			t.Errorf("status %d: want error, got nil", c.StatusCode)
+		}
+		assertEqual(t, c.Body.(*closeWatcher).CloseCalls, 1)
+	}
+}
+
+func TestGetConditionalTimings(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(delay)
+		_, _ = io.WriteString(w, `{"name":`)
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		_, _ = io.WriteString(w, `"v1"}`)
+	}))
+	defer server.Close()
+
+	res, _, err := xhttp.GetConditional[resource](context.Background(), server.Client(), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.Body, resource{Name: "v1"})
+	if res.TTFB < delay {
+		t.Errorf("got TTFB %v; want >= %v", res.TTFB, delay)
+	}
+	if res.Elapsed < res.TTFB+delay {
+		t.Errorf("got elapsed %v; want >= TTFB %v + %v", res.Elapsed, res.TTFB, delay)
+	}
+
+	// Fake clients don't support tracing
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))})
+	res, _, err = xhttp.GetConditional[resource](context.Background(), fakeClient, "http://test/resource", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.TTFB, time.Duration(0))
+}
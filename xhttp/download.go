@@ -0,0 +1,57 @@
+package xhttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Download sends a GET request (created with [NewRequestWithContext]) for the given url using the given client
+// and streams the response body to w, returning the number of bytes written.
+// The response body is never buffered entirely in memory, so it is useful for downloading big files.
+// If the response has a non 2xx status code the response body is discarded and an error is returned.
+//
+// Copying stops with the context error if [ctx] is cancelled. The response body is always closed.
+// It works with clients created with [NewRetrierClient] as long as [RetrierWithRespCheck] is not used,
+// since that reads the entire response body in memory.
+func Download(ctx context.Context, c Client, url string, w io.Writer) (int64, error) {
+	req, err := NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("xhttp.Download: creating request: %w", err)
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("xhttp.Download: sending request: %w", err)
+	}
+	defer closeBody(ctx, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// Drain (part of) the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainSize))
+		return 0, fmt.Errorf("xhttp.Download: unexpected status %d", res.StatusCode)
+	}
+
+	n, err := io.Copy(w, &ctxReader{ctx: ctx, r: res.Body})
+	if err != nil {
+		return n, fmt.Errorf("xhttp.Download: copying response body: %w", err)
+	}
+	return n, nil
+}
+
+// maxDrainSize is the max amount of bytes read from response bodies that are discarded.
+const maxDrainSize = 64 << 10
+
+// ctxReader stops reading when the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
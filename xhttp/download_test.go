@@ -0,0 +1,86 @@
+package xhttp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestDownload(t *testing.T) {
+	const content = "file contents"
+
+	body := watchClose(strings.NewReader(content))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       body,
+	})
+
+	var file bytes.Buffer
+	n, err := xhttp.Download(context.Background(), fakeClient, "http://test/file", &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, n, int64(len(content)))
+	assertEqual(t, file.String(), content)
+	assertEqual(t, body.CloseCalls, 1)
+
+	req := fakeClient.Requests()[0]
+	assertEqual(t, req.Method, http.MethodGet)
+	assertEqual(t, req.URL.String(), "http://test/file")
+}
+
+func TestDownloadErrorStatus(t *testing.T) {
+	body := watchClose(strings.NewReader("not found"))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusNotFound,
+		Body:       body,
+	})
+
+	var file bytes.Buffer
+	_, err := xhttp.Download(context.Background(), fakeClient, "http://test/file", &file)
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	assertEqual(t, file.Len(), 0)
+	assertEqual(t, body.CloseCalls, 1)
+}
+
+func TestDownloadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	body := watchClose(io.MultiReader(strings.NewReader("first"), readerFunc(func([]byte) (int, error) {
+		cancel()
+		return 0, nil
+	}), strings.NewReader("never read")))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       body,
+	})
+
+	var file bytes.Buffer
+	_, err := xhttp.Download(ctx, fakeClient, "http://test/file", &file)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v; want %v", err, context.Canceled)
+	}
+	if strings.Contains(file.String(), "never read") {
+		t.Fatalf("got file %q; want copy to stop after cancel", file.String())
+	}
+	assertEqual(t, body.CloseCalls, 1)
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,144 @@
+package xhttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+type (
+	// DumpOption is used to configure dump clients created with [NewDumpClient].
+	DumpOption func(*dumpClient)
+
+	dumpClient struct {
+		client          Client
+		maxBodySize     int
+		redactedHeaders []string
+		mutex           sync.Mutex
+		w               io.Writer
+	}
+)
+
+// NewDumpClient wraps the given client, writing the raw wire representation of each request and response
+// (see [httputil.DumpRequestOut] and [httputil.DumpResponse]) to w. It is intended for debugging integrations.
+// When composed with [NewRetrierClient], like NewRetrierClient(NewDumpClient(c, w)), each attempt is dumped.
+//
+// Request and response bodies are read entirely in memory, so they are dumped and still sent/returned as is.
+// By default bodies are dumped entirely and the Authorization, Cookie and Set-Cookie headers are redacted,
+// see [DumpWithMaxBodySize] and [DumpWithRedactedHeaders].
+// It is safe to use the client concurrently, each request/response is written to w with a single Write call.
+// Failing to write to w does not fail requests, the failure is only logged.
+func NewDumpClient(c Client, w io.Writer, options ...DumpOption) Client {
+	d := &dumpClient{
+		client:          c,
+		w:               w,
+		redactedHeaders: []string{"Authorization", "Cookie", "Set-Cookie"},
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// DumpWithMaxBodySize configures the max size in bytes of request and response bodies written by the dump client,
+// bodies bigger than that are truncated (only on the dump). If size <= 0 bodies are not truncated (the default).
+func DumpWithMaxBodySize(size int) DumpOption {
+	return func(d *dumpClient) {
+		d.maxBodySize = size
+	}
+}
+
+// DumpWithRedactedHeaders configures headers that have their values redacted on dumps.
+// The given headers are redacted in addition to the default ones (Authorization, Cookie and Set-Cookie).
+func DumpWithRedactedHeaders(headers ...string) DumpOption {
+	return func(d *dumpClient) {
+		d.redactedHeaders = append(d.redactedHeaders, headers...)
+	}
+}
+
+func (d *dumpClient) Do(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, fmt.Errorf("closing request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	log := slog.FromCtx(req.Context()).With("request_url", req.URL)
+
+	dumpReq := req.Clone(req.Context())
+	dumpReq.Header = d.redact(req.Header)
+	reqDump, err := httputil.DumpRequestOut(dumpReq, false)
+	if err != nil {
+		log.Debug("xhttp.DumpClient: dumping request", "error", err)
+	} else {
+		d.write(log, reqDump, requestBody)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		d.write(log, []byte(fmt.Sprintf("error: %v\n", err)), nil)
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(res.Body)
+	if cerr := res.Body.Close(); cerr != nil {
+		log.Debug("xhttp.DumpClient: error closing response body", "error", cerr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	dumpRes := *res
+	dumpRes.Header = d.redact(res.Header)
+	dumpRes.Body = io.NopCloser(bytes.NewReader(responseBody))
+	resDump, err := httputil.DumpResponse(&dumpRes, false)
+	if err != nil {
+		log.Debug("xhttp.DumpClient: dumping response", "error", err)
+	} else {
+		d.write(log, resDump, responseBody)
+	}
+
+	return res, nil
+}
+
+func (d *dumpClient) redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range d.redactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+func (d *dumpClient) write(log *slog.Logger, dump []byte, body []byte) {
+	var buf bytes.Buffer
+	buf.Write(dump)
+	if d.maxBodySize > 0 && len(body) > d.maxBodySize {
+		buf.Write(body[:d.maxBodySize])
+		fmt.Fprintf(&buf, "... (truncated %d bytes)", len(body)-d.maxBodySize)
+	} else {
+		buf.Write(body)
+	}
+	buf.WriteString("\n")
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, err := d.w.Write(buf.Bytes()); err != nil {
+		log.Debug("xhttp.DumpClient: writing dump", "error", err)
+	}
+}
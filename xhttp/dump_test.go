@@ -0,0 +1,90 @@
+package xhttp_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestDumpClient(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Set-Cookie": []string{"session=secret"}, "X-Response": []string{"res-header"}},
+		Body:       io.NopCloser(strings.NewReader("response body")),
+	})
+
+	var dump bytes.Buffer
+	client := xhttp.NewDumpClient(fakeClient, &dump,
+		xhttp.DumpWithMaxBodySize(7),
+		xhttp.DumpWithRedactedHeaders("X-Api-Key"))
+
+	req := newRequest(t, http.MethodPost, "http://test/path", []byte("request body"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("X-Request", "req-header")
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bodies and headers are kept intact
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(resBody), "response body")
+	assertEqual(t, res.Header.Get("Set-Cookie"), "session=secret")
+
+	sentReq := fakeClient.Requests()[0]
+	sentBody, err := io.ReadAll(sentReq.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(sentBody), "request body")
+	assertEqual(t, sentReq.Header.Get("Authorization"), "Bearer secret")
+
+	got := dump.String()
+	for _, want := range []string{
+		"POST /path HTTP/1.1",
+		"X-Request: req-header",
+		"Authorization: REDACTED",
+		"X-Api-Key: REDACTED",
+		"request... (truncated 5 bytes)",
+		"HTTP/1.1 200 OK",
+		"X-Response: res-header",
+		"Set-Cookie: REDACTED",
+		"respons... (truncated 6 bytes)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dump missing %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("dump has secrets:\n%s", got)
+	}
+}
+
+func TestDumpClientError(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushError(retryableError())
+
+	var dump bytes.Buffer
+	client := xhttp.NewDumpClient(fakeClient, &dump)
+
+	_, err := client.Do(newRequest(t, http.MethodGet, "http://test", nil))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if got := dump.String(); !strings.Contains(got, "error: "+err.Error()) {
+		t.Fatalf("dump missing error %v, got:\n%s", err, got)
+	}
+}
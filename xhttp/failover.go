@@ -0,0 +1,104 @@
+package xhttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+type (
+	// FailoverOption is used to configure failover clients created with [NewFailoverClient].
+	FailoverOption func(*failoverClient)
+
+	failoverClient struct {
+		client    Client
+		hosts     []string
+		sticky    bool
+		preferred atomic.Int64
+	}
+)
+
+// NewFailoverClient wraps the given client with host failover. Requests are sent to the given hosts in order
+// (the URL host of requests is replaced by each host, like "api.example.com" or "10.0.0.1:8080"), until one of them
+// answers. Only transport errors (the wrapped client failing) fail over, any response (including error statuses) is returned
+// as is. If all hosts fail an error with all the failures is returned. If the request context is done it stops failing over.
+//
+// The request body is read entirely in memory, so it can be sent to each host.
+// By default each request starts from the first host, use [FailoverWithStickyHost] to prefer the last host that answered.
+// When composed with [NewRetrierClient], like NewRetrierClient(NewFailoverClient(c, hosts)), each retry fails over all hosts.
+// It panics if no hosts are given.
+func NewFailoverClient(c Client, hosts []string, options ...FailoverOption) Client {
+	if len(hosts) == 0 {
+		panic("xhttp: creating failover client: hosts can't be empty")
+	}
+	f := &failoverClient{
+		client: c,
+		hosts:  hosts,
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// FailoverWithStickyHost configures the failover client to start sending requests to the last host that answered,
+// instead of always starting from the first host, avoiding failing over on every request while a host is down.
+func FailoverWithStickyHost() FailoverOption {
+	return func(f *failoverClient) {
+		f.sticky = true
+	}
+}
+
+func (f *failoverClient) Do(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, fmt.Errorf("closing request body: %w", err)
+		}
+	}
+
+	ctx := req.Context()
+	start := 0
+	if f.sticky {
+		start = int(f.preferred.Load())
+	}
+
+	var errs []error
+	for i := range len(f.hosts) {
+		hostIndex := (start + i) % len(f.hosts)
+		host := f.hosts[hostIndex]
+
+		hostReq := req.Clone(ctx)
+		hostReq.URL.Host = host
+		// The Host header must match the new host
+		hostReq.Host = ""
+		if req.Body != nil {
+			hostReq.Body = io.NopCloser(bytes.NewReader(requestBody))
+		}
+
+		res, err := f.client.Do(hostReq)
+		if err == nil {
+			if f.sticky {
+				f.preferred.Store(int64(hostIndex))
+			}
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("host %q: %w", host, err))
+		if ctx.Err() != nil {
+			break
+		}
+		slog.FromCtx(ctx).Debug("xhttp.FailoverClient: request failed, failing over to next host",
+			"host", host, "error", err)
+	}
+	return nil, fmt.Errorf("all hosts failed: %w", errors.Join(errs...))
+}
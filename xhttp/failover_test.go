@@ -0,0 +1,100 @@
+package xhttp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestFailoverClient(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewFailoverClient(fakeClient, []string{"a", "b:8080", "c"})
+
+	fakeClient.PushError(errors.New("connection refused"))
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))})
+
+	res, err := client.Do(newRequest(t, http.MethodPost, "http://original/path?q=1", []byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Error statuses don't fail over
+	assertEqual(t, res.StatusCode, http.StatusServiceUnavailable)
+
+	requests := fakeClient.Requests()
+	assertEqual(t, len(requests), 2)
+	assertEqual(t, requests[0].URL.String(), "http://a/path?q=1")
+	assertEqual(t, requests[1].URL.String(), "http://b:8080/path?q=1")
+	for _, req := range requests {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, string(body), "body")
+	}
+
+	// Not sticky, next request starts from the first host again
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))})
+	if _, err := client.Do(newRequest(t, http.MethodGet, "http://original/path", nil)); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, fakeClient.Requests()[2].URL.Host, "a")
+}
+
+func TestFailoverClientStickyHost(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewFailoverClient(fakeClient, []string{"a", "b", "c"}, xhttp.FailoverWithStickyHost())
+
+	fakeClient.PushError(errors.New("a down"))
+	fakeClient.PushError(errors.New("b down"))
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))})
+	fakeClient.PushError(errors.New("c down"))
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))})
+
+	for range 2 {
+		if _, err := client.Do(newRequest(t, http.MethodGet, "http://original/path", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var gotHosts []string
+	for _, req := range fakeClient.Requests() {
+		gotHosts = append(gotHosts, req.URL.Host)
+	}
+	assertEqual(t, gotHosts, []string{"a", "b", "c", "c", "a"})
+}
+
+func TestFailoverClientAllHostsFail(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewFailoverClient(fakeClient, []string{"a", "b"})
+
+	errA := errors.New("a down")
+	errB := errors.New("b down")
+	fakeClient.PushError(errA)
+	fakeClient.PushError(errB)
+
+	_, err := client.Do(newRequest(t, http.MethodGet, "http://original/path", nil))
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got error %v; want errors of all hosts", err)
+	}
+}
+
+func TestFailoverClientStopsOnCancelledContext(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewFailoverClient(fakeClient, []string{"a", "b"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fakeClient.PushError(context.Canceled)
+
+	req := newRequest(t, http.MethodGet, "http://original/path", nil).WithContext(ctx)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+	assertEqual(t, len(fakeClient.Requests()), 1)
+}
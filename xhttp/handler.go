@@ -0,0 +1,112 @@
+package xhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+type (
+	// JSONHandlerFunc handles a request parsed by [JSONHandler]. It returns the value that is sent as the JSON response body
+	// (no body is sent if it is nil) and the status code of the response (200 if zero).
+	// If an error is returned the value is ignored and an error response is sent instead, see [JSONHandler].
+	JSONHandlerFunc[T any] func(ctx context.Context, req T) (any, int, error)
+
+	// JSONHandlerOption is used to configure handlers created with [JSONHandler].
+	JSONHandlerOption func(*jsonHandlerConfig)
+
+	jsonHandlerConfig struct {
+		maxBodySize int64
+	}
+)
+
+// DefaultJSONHandlerMaxBodySize is the default max size of request bodies accepted by [JSONHandler].
+const DefaultJSONHandlerMaxBodySize = 1 << 20
+
+// JSONHandler creates a [http.Handler] that parses the JSON request body as [T], calls the given function with it
+// and sends the returned value as the JSON response body. The context passed to the function is the request context,
+// so if the handler is instrumented (see the tracing package) it has the request tracing information and logger.
+//
+// Requests with bodies that are not valid JSON (or can't be parsed as [T]) get a 400 (Bad Request) response, and requests
+// with bodies bigger than the max body size (see [JSONHandlerWithMaxBodySize]) get a 413 (Request Entity Too Large) response.
+// If the function returns an error it is logged and the response has the returned status (500 if it is not an error status).
+// Error responses have a JSON body like {"error": "message"}, with the error message for client errors (4xx) and only
+// the status text for server errors (5xx), so internal errors are not leaked to clients.
+func JSONHandler[T any](fn JSONHandlerFunc[T], options ...JSONHandlerOption) http.Handler {
+	cfg := jsonHandlerConfig{
+		maxBodySize: DefaultJSONHandlerMaxBodySize,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		log := slog.FromCtx(ctx).With("method", req.Method, "path", req.URL.Path)
+
+		var body T
+		if err := json.NewDecoder(http.MaxBytesReader(res, req.Body, cfg.maxBodySize)).Decode(&body); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				log.Warn("xhttp.JSONHandler: request body too large", "max_body_size", cfg.maxBodySize)
+				writeJSONError(log, res, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			log.Warn("xhttp.JSONHandler: parsing request body", "error", err)
+			writeJSONError(log, res, http.StatusBadRequest, fmt.Errorf("parsing request body: %w", err))
+			return
+		}
+
+		value, status, err := fn(ctx, body)
+		if err != nil {
+			if status < 400 {
+				status = http.StatusInternalServerError
+			}
+			log.Error("xhttp.JSONHandler: handling request", "error", err, "status", status)
+			writeJSONError(log, res, status, err)
+			return
+		}
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeJSON(log, res, status, value)
+	})
+}
+
+// JSONHandlerWithMaxBodySize configures the max size in bytes of request bodies accepted by [JSONHandler].
+// If not defined it will default to [DefaultJSONHandlerMaxBodySize].
+func JSONHandlerWithMaxBodySize(size int64) JSONHandlerOption {
+	return func(cfg *jsonHandlerConfig) {
+		cfg.maxBodySize = size
+	}
+}
+
+func writeJSONError(log *slog.Logger, res http.ResponseWriter, status int, err error) {
+	message := http.StatusText(status)
+	if status < 500 {
+		message = err.Error()
+	}
+	writeJSON(log, res, status, map[string]string{"error": message})
+}
+
+func writeJSON(log *slog.Logger, res http.ResponseWriter, status int, value any) {
+	if value == nil {
+		res.WriteHeader(status)
+		return
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		log.Error("xhttp.JSONHandler: encoding response body", "error", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	if _, err := res.Write(body); err != nil {
+		log.Debug("xhttp.JSONHandler: writing response body", "error", err)
+	}
+}
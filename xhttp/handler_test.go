@@ -0,0 +1,72 @@
+package xhttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+)
+
+type (
+	sumRequest struct {
+		Values []int `json:"values"`
+	}
+	sumResponse struct {
+		Sum int `json:"sum"`
+	}
+)
+
+func sumHandler(_ context.Context, req sumRequest) (any, int, error) {
+	if len(req.Values) == 0 {
+		return nil, http.StatusUnprocessableEntity, errors.New("no values")
+	}
+	if req.Values[0] < 0 {
+		return nil, 0, errors.New("internal failure")
+	}
+	sum := 0
+	for _, v := range req.Values {
+		sum += v
+	}
+	if sum == 0 {
+		return nil, http.StatusNoContent, nil
+	}
+	return sumResponse{Sum: sum}, 0, nil
+}
+
+func TestJSONHandler(t *testing.T) {
+	handler := xhttp.JSONHandler(sumHandler, xhttp.JSONHandlerWithMaxBodySize(32))
+
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"ok", `{"values":[1,2,3]}`, http.StatusOK, `{"sum":6}`},
+		{"no body", `{"values":[0]}`, http.StatusNoContent, ""},
+		{"invalid json", `{"values":`, http.StatusBadRequest, `{"error":"parsing request body: unexpected EOF"}`},
+		{"wrong type", `{"values":"1"}`, http.StatusBadRequest, ""},
+		{"too large", `{"values":[` + strings.Repeat("1,", 32) + `1]}`, http.StatusRequestEntityTooLarge, ""},
+		{"client error", `{"values":[]}`, http.StatusUnprocessableEntity, `{"error":"no values"}`},
+		{"server error", `{"values":[-1]}`, http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/sum", strings.NewReader(c.body)))
+
+			assertEqual(t, res.Code, c.wantStatus)
+			if c.wantBody != "" {
+				assertEqual(t, res.Body.String(), c.wantBody)
+				assertEqual(t, res.Header().Get("Content-Type"), "application/json")
+			}
+			if c.wantStatus == http.StatusNoContent {
+				assertEqual(t, res.Body.Len(), 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package xhttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderInt gets the header with the given key and parses it as an integer.
+// It returns false if the header is absent or is not a valid integer.
+func HeaderInt(h http.Header, key string) (int, bool) {
+	v := strings.TrimSpace(h.Get(key))
+	if v == "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+// HeaderDuration gets the header with the given key and parses it as a duration in seconds,
+// which is the usual representation of durations in HTTP headers (like Retry-After or rate-limit headers).
+// It returns false if the header is absent or is not a valid integer.
+func HeaderDuration(h http.Header, key string) (time.Duration, bool) {
+	seconds, ok := HeaderInt(h, key)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// HeaderTime gets the header with the given key and parses it as a time using [http.ParseTime].
+// It returns false if the header is absent or is not a valid HTTP time.
+func HeaderTime(h http.Header, key string) (time.Time, bool) {
+	v := strings.TrimSpace(h.Get(key))
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,77 @@
+package xhttp_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/xhttp"
+)
+
+func TestHeaderInt(t *testing.T) {
+	cases := []struct {
+		value  string
+		want   int
+		wantOk bool
+	}{
+		{value: "", want: 0, wantOk: false},
+		{value: "abc", want: 0, wantOk: false},
+		{value: "1.5", want: 0, wantOk: false},
+		{value: "10", want: 10, wantOk: true},
+		{value: " -3 ", want: -3, wantOk: true},
+	}
+	for _, c := range cases {
+		h := http.Header{}
+		if c.value != "" {
+			h.Set("X-Test", c.value)
+		}
+		got, ok := xhttp.HeaderInt(h, "X-Test")
+		if got != c.want || ok != c.wantOk {
+			t.Errorf("xhttp.HeaderInt(%q) == (%v, %v), want (%v, %v)", c.value, got, ok, c.want, c.wantOk)
+		}
+	}
+}
+
+func TestHeaderDuration(t *testing.T) {
+	cases := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{value: "", want: 0, wantOk: false},
+		{value: "1s", want: 0, wantOk: false},
+		{value: "30", want: 30 * time.Second, wantOk: true},
+	}
+	for _, c := range cases {
+		h := http.Header{}
+		if c.value != "" {
+			h.Set("X-Test", c.value)
+		}
+		got, ok := xhttp.HeaderDuration(h, "X-Test")
+		if got != c.want || ok != c.wantOk {
+			t.Errorf("xhttp.HeaderDuration(%q) == (%v, %v), want (%v, %v)", c.value, got, ok, c.want, c.wantOk)
+		}
+	}
+}
+
+func TestHeaderTime(t *testing.T) {
+	cases := []struct {
+		value  string
+		want   time.Time
+		wantOk bool
+	}{
+		{value: "", wantOk: false},
+		{value: "123", wantOk: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC), wantOk: true},
+	}
+	for _, c := range cases {
+		h := http.Header{}
+		if c.value != "" {
+			h.Set("X-Test", c.value)
+		}
+		got, ok := xhttp.HeaderTime(h, "X-Test")
+		if !got.Equal(c.want) || ok != c.wantOk {
+			t.Errorf("xhttp.HeaderTime(%q) == (%v, %v), want (%v, %v)", c.value, got, ok, c.want, c.wantOk)
+		}
+	}
+}
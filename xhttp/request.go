@@ -1,10 +1,14 @@
 package xhttp
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"runtime/debug"
+	"slices"
 	"strings"
 )
 
@@ -60,3 +64,79 @@ func NewRequestWithContext(ctx context.Context, method, url string, body io.Read
 	req.Header.Set("User-Agent", defaultUserAgent)
 	return req, nil
 }
+
+// NewRequestWithUserAgent is like [NewRequestWithContext] but sets the given userAgent as the User-Agent header.
+// If userAgent is empty the same default User-Agent of [NewRequestWithContext] is used.
+// Useful for APIs that require clients to identify themselves with a registered User-Agent.
+func NewRequestWithUserAgent(ctx context.Context, method, url string, body io.Reader, userAgent string) (*http.Request, error) {
+	req, err := NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return req, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	return req, nil
+}
+
+// WithBearer sets the Authorization header of the given request with the given bearer token, as defined by [RFC 6750].
+// See [RetrierWithBearer] to set the token on all requests sent by a retrier client.
+//
+// [RFC 6750]: https://datatracker.ietf.org/doc/html/rfc6750#section-2.1
+func WithBearer(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// WithBasicAuth sets the Authorization header of the given request to use HTTP Basic Authentication, like [http.Request.SetBasicAuth].
+// See [RetrierWithBasicAuth] to set the credentials on all requests sent by a retrier client.
+func WithBasicAuth(req *http.Request, user, pass string) {
+	req.SetBasicAuth(user, pass)
+}
+
+// NewMultipartRequest creates a POST request (using [NewRequestWithContext]) with a multipart/form-data body
+// containing the given fields and files, setting the Content-Type header with the body boundary.
+// Each file is a form file where the map key is used both as field name and file name.
+// Fields and files are written sorted by their names.
+//
+// The body is buffered in memory (files are read entirely), so the request has a known Content-Length and
+// can be sent multiple times, like when using [NewRetrierClient] (which buffers request bodies anyway).
+func NewMultipartRequest(ctx context.Context, url string, fields map[string]string, files map[string]io.Reader) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, name := range sortedKeys(fields) {
+		if err := writer.WriteField(name, fields[name]); err != nil {
+			return nil, fmt.Errorf("writing multipart field %q: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(files) {
+		part, err := writer.CreateFormFile(name, name)
+		if err != nil {
+			return nil, fmt.Errorf("creating multipart file %q: %w", name, err)
+		}
+		if _, err := io.Copy(part, files[name]); err != nil {
+			return nil, fmt.Errorf("writing multipart file %q: %w", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("closing multipart writer: %w", err)
+	}
+
+	req, err := NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
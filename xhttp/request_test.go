@@ -2,8 +2,10 @@ package xhttp_test
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"testing"
 
 	"github.com/birdie-ai/golibs/xhttp"
@@ -18,10 +20,104 @@ func TestRequestUserAgent(t *testing.T) {
 	if !ok {
 		t.Fatal("test supposed to have build information")
 	}
-	// The test only has the Go version, no main build info available
 	want := "Go/" + bf.GoVersion
+	// Since Go 1.24 test binaries also have the path of the main package (like ".../xhttp.test"),
+	// but they have no VCS information, so there is no version.
+	if bf.Path != "" {
+		ppath := strings.Split(bf.Path, "/")
+		want = ppath[len(ppath)-1] + "/no-version " + want
+	}
 
 	if want != v.UserAgent() {
 		t.Fatalf("got user agent %q; want %q", v.UserAgent(), want)
 	}
 }
+
+func TestRequestWithUserAgent(t *testing.T) {
+	const want = "custom-agent/1.0"
+
+	v, err := xhttp.NewRequestWithUserAgent(context.Background(), http.MethodGet, "http://test", nil, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.UserAgent(); got != want {
+		t.Fatalf("got user agent %q; want %q", got, want)
+	}
+
+	v, err = xhttp.NewRequestWithUserAgent(context.Background(), http.MethodGet, "http://test", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultReq, err := xhttp.NewRequestWithContext(context.Background(), http.MethodGet, "http://test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.UserAgent(), defaultReq.UserAgent(); got != want {
+		t.Fatalf("got user agent %q; want default %q", got, want)
+	}
+}
+
+func TestNewMultipartRequest(t *testing.T) {
+	fields := map[string]string{"name": "report", "kind": "csv"}
+	files := map[string]io.Reader{"data.csv": strings.NewReader("a,b\n1,2\n")}
+
+	req, err := xhttp.NewMultipartRequest(context.Background(), "http://test/upload", fields, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodPost {
+		t.Fatalf("got method %q; want %q", req.Method, http.MethodPost)
+	}
+	defaultReq, err := xhttp.NewRequestWithContext(context.Background(), http.MethodGet, "http://test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := req.UserAgent(), defaultReq.UserAgent(); got != want {
+		t.Fatalf("got user agent %q; want default %q", got, want)
+	}
+
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range fields {
+		if got := req.FormValue(name); got != want {
+			t.Errorf("got field %q = %q; want %q", name, got, want)
+		}
+	}
+
+	file, header, err := req.FormFile("data.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFile, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if header.Filename != "data.csv" {
+		t.Errorf("got file name %q; want %q", header.Filename, "data.csv")
+	}
+	if string(gotFile) != "a,b\n1,2\n" {
+		t.Errorf("got file contents %q", gotFile)
+	}
+}
+
+func TestRequestAuth(t *testing.T) {
+	req, err := xhttp.NewRequestWithContext(context.Background(), http.MethodGet, "http://test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xhttp.WithBearer(req, "token")
+	if got, want := req.Header.Get("Authorization"), "Bearer token"; got != want {
+		t.Fatalf("got Authorization %q; want %q", got, want)
+	}
+
+	xhttp.WithBasicAuth(req, "user", "pass")
+	user, pass, ok := req.BasicAuth()
+	if !ok || user != "user" || pass != "pass" {
+		t.Fatalf("got basic auth %q:%q (ok=%v); want user:pass", user, pass, ok)
+	}
+}
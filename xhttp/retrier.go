@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/birdie-ai/golibs/slog"
+	"github.com/google/uuid"
 )
 
 type (
@@ -40,8 +41,43 @@ type (
 	// The [error] is the response error returned by the [Client.Do] call.
 	// This is called every time a request is retried.
 	RetrierOnRetryFunc func(req *http.Request, res *http.Response, err error)
+
+	// RetrierBackoffFunc is the backoff strategy used by retrier clients, configured with [RetrierWithBackoffStrategy].
+	// It returns how long to sleep before the given retry attempt (starting at 1), where base is the
+	// min sleep period (see [RetrierWithMinSleepPeriod]) and max is the max sleep period (see [RetrierWithMaxSleepPeriod]).
+	RetrierBackoffFunc func(attempt int, base, max time.Duration) time.Duration
+
+	// RetryError is the error returned by retrier clients created with [NewRetrierClient] when they give up
+	// after sending at least one request. It has the history of all attempts and can be retrieved with [errors.As].
+	// The final error (like a non retryable error or the context error) is wrapped, so it can be checked with [errors.Is].
+	RetryError struct {
+		// Attempts has one entry for each request sent, in order.
+		Attempts []RetryAttempt
+		// Err is the error that made the retrier give up.
+		Err error
+	}
+
+	// RetryAttempt is the record of a single request sent by a retrier client.
+	RetryAttempt struct {
+		// Time is when the request was sent.
+		Time time.Time
+		// Elapsed is how long the request took.
+		Elapsed time.Duration
+		// StatusCode is the status code of the response, zero if no response was received.
+		StatusCode int
+		// Err is the error of the request (or reading the response body), nil if a response was received.
+		Err error
+	}
 )
 
+// ErrTransient can be used to mark errors as transient, making the retrier client retry them.
+// Errors are marked by wrapping, like fmt.Errorf("%w: %w", xhttp.ErrTransient, err) or errors.Join(xhttp.ErrTransient, err).
+// This is useful for custom [Client] implementations (or transports) wrapped by [NewRetrierClient] that know that
+// some of their failures are transient.
+// Errors marked with ErrTransient are always retried, this is checked in addition to the built-in
+// heuristics of the retrier (like retrying connection reset errors), which are still applied to unmarked errors.
+var ErrTransient = errors.New("transient error")
+
 const (
 	// DefaultMinSleepPeriod is the min sleep period between retries (which is increased exponentially).
 	DefaultMinSleepPeriod = 250 * time.Millisecond
@@ -78,10 +114,15 @@ type (
 		minPeriod        time.Duration
 		maxPeriod        time.Duration
 		checkResponse    bool
+		userAgent        string
+		idempotencyKey   string
 		sleep            func(context.Context, time.Duration)
 		retryStatusCodes map[int]struct{}
 		onRequestDone    RetrierOnRequestDoneFunc
 		onRetry          RetrierOnRetryFunc
+		backoff          RetrierBackoffFunc
+		auth             func(*http.Request)
+		shouldRetry      func(*http.Response) bool
 	}
 	readerCloserCanceller struct {
 		io.ReadCloser
@@ -108,21 +149,64 @@ func (r *retrierClient) Do(req *http.Request) (*http.Response, error) {
 		}
 	}
 
-	return r.do(req.Context(), req, requestBody, r.minPeriod)
+	req = r.setHeaders(req)
+
+	var attempts []RetryAttempt
+	res, err := r.do(req.Context(), req, requestBody, &attempts, 0)
+	if err != nil && len(attempts) > 0 {
+		return nil, &RetryError{Attempts: attempts, Err: err}
+	}
+	return res, err
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", len(e.Attempts), e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// setHeaders sets headers that are the same for all attempts of a request.
+func (r *retrierClient) setHeaders(req *http.Request) *http.Request {
+	setUserAgent := r.userAgent != "" && req.Header.Get("User-Agent") == ""
+	setIdempotencyKey := r.idempotencyKey != "" && req.Header.Get(r.idempotencyKey) == ""
+	if !setUserAgent && !setIdempotencyKey && r.auth == nil {
+		return req
+	}
+
+	// Avoid changing the headers of the caller's request (including the ones set on each attempt)
+	req = req.Clone(req.Context())
+	if setUserAgent {
+		req.Header.Set("User-Agent", r.userAgent)
+	}
+	if setIdempotencyKey {
+		req.Header.Set(r.idempotencyKey, uuid.NewString())
+	}
+	return req
 }
 
-func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody []byte, sleepPeriod time.Duration) (*http.Response, error) {
+func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody []byte, attempts *[]RetryAttempt, prevSleepPeriod time.Duration) (*http.Response, error) {
 	if ctx.Err() != nil {
 		slog.FromCtx(ctx).Debug("xhttp.Client: stopping retry: parent context canceled", "error", ctx.Err())
 		return nil, ctx.Err()
 	}
-	req, cancel := r.newRequest(ctx, req, requestBody)
+	req, cancel := r.newRequest(ctx, req, requestBody, len(*attempts))
 
 	log := slog.FromCtx(ctx).With("request_url", req.URL)
 
 	start := time.Now()
 	res, err := r.client.Do(req)
-	r.onRequestDone(req, res, err, time.Since(start))
+	elapsed := time.Since(start)
+	r.onRequestDone(req, res, err, elapsed)
+
+	attempt := RetryAttempt{Time: start, Elapsed: elapsed, Err: err}
+	if res != nil {
+		attempt.StatusCode = res.StatusCode
+	}
+	*attempts = append(*attempts, attempt)
+	sleepPeriod := r.nextSleepPeriod(len(*attempts), prevSleepPeriod)
+
 	if err != nil {
 		cancel()
 
@@ -133,7 +217,8 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 		// For connections reset... Same problem:
 		// - https://github.com/golang/go/blob/d0dc93c8e1a5be4e0a44b7f8ecb0cb1417de50ce/src/net/http/transport_test.go#L2207
 		emsg := err.Error()
-		if errors.Is(err, context.DeadlineExceeded) ||
+		if errors.Is(err, ErrTransient) ||
+			errors.Is(err, context.DeadlineExceeded) ||
 			strings.Contains(emsg, "http2: server sent GOAWAY and closed the connection") ||
 			strings.HasSuffix(emsg, "i/o timeout") ||
 			strings.HasSuffix(emsg, "connect: connection refused") ||
@@ -148,7 +233,7 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 			log.Debug("xhttp.Client: retrying request with error", "error", err, "sleep_period", sleepPeriod.String())
 			r.onRetry(req, res, err)
 			r.sleep(ctx, sleepPeriod)
-			return r.do(ctx, req, requestBody, min(sleepPeriod*2, r.maxPeriod))
+			return r.do(ctx, req, requestBody, attempts, sleepPeriod)
 		}
 
 		log.Debug("xhttp.Client: non recoverable error", "error", err)
@@ -158,6 +243,22 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 	res.Body = &readerCloserCanceller{res.Body, cancel}
 
 	_, isRetryCode := r.retryStatusCodes[res.StatusCode]
+	if !isRetryCode && r.shouldRetry != nil && res.StatusCode >= http.StatusBadRequest {
+		respBodyBytes, err := io.ReadAll(res.Body)
+		if cerr := res.Body.Close(); cerr != nil {
+			log.Debug("xhttp.Client: error closing response body", "error", cerr)
+		}
+		if err != nil {
+			log.Debug("xhttp.Client: retrying request with error reading response body", "error", err)
+			(*attempts)[len(*attempts)-1].Err = err
+			r.sleep(ctx, sleepPeriod)
+			return r.do(ctx, req, requestBody, attempts, sleepPeriod)
+		}
+		res.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
+		isRetryCode = r.shouldRetry(res)
+		// The callback may have read the body, so the caller always gets the whole body
+		res.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
+	}
 	if isRetryCode {
 		log := slog.FromCtx(ctx).With("status_code", res.StatusCode, "sleep_period", sleepPeriod.String())
 		if err := res.Body.Close(); err != nil {
@@ -186,7 +287,7 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 		}
 
 		r.sleep(ctx, sleepPeriod)
-		return r.do(ctx, req, requestBody, min(sleepPeriod*2, r.maxPeriod))
+		return r.do(ctx, req, requestBody, attempts, sleepPeriod)
 	}
 
 	if r.checkResponse {
@@ -199,8 +300,9 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 		}
 		if err != nil {
 			log.Debug("xhttp.Client: retrying request with error reading response body", "error", err)
+			(*attempts)[len(*attempts)-1].Err = err
 			r.sleep(ctx, sleepPeriod)
-			return r.do(ctx, req, requestBody, min(sleepPeriod*2, r.maxPeriod))
+			return r.do(ctx, req, requestBody, attempts, sleepPeriod)
 		}
 		log.Debug("xhttp.Client: response body read with success")
 		res.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
@@ -209,17 +311,73 @@ func (r *retrierClient) do(ctx context.Context, req *http.Request, requestBody [
 	return res, nil
 }
 
-func (r *retrierClient) newRequest(ctx context.Context, req *http.Request, requestBody []byte) (*http.Request, context.CancelFunc) {
+func (r *retrierClient) newRequest(ctx context.Context, req *http.Request, requestBody []byte, attempt int) (*http.Request, context.CancelFunc) {
 	// We need to always guarantee that the request has a readable io.Reader for the original request body
 	req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	if r.auth != nil {
+		r.auth(req)
+	}
+	attemptCtx := context.WithValue(ctx, attemptKey, attempt)
 	if r.requestTimeout == 0 {
-		return req, func() {}
+		return req.WithContext(attemptCtx), func() {}
 	}
-	newCtx, cancel := context.WithTimeout(ctx, r.requestTimeout)
+	newCtx, cancel := context.WithTimeout(attemptCtx, r.requestTimeout)
 	newReq := req.Clone(newCtx)
 	return newReq, cancel
 }
 
+// AttemptFromContext returns the attempt number of the request being sent by a retrier client (see [NewRetrierClient]),
+// starting at 0 for the first attempt, 1 for the first retry and so on. It is available on the context of requests sent
+// by the retrier, so clients (or transports) wrapped by the retrier can use it, like for logging and metrics.
+// It returns 0 if the context has no attempt number.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey).(int)
+	return attempt
+}
+
+// key is the type used to store data on contexts.
+type key int
+
+const (
+	attemptKey key = iota
+)
+
+// nextSleepPeriod returns how long to sleep before retrying the given attempt. Without a configured backoff strategy the
+// previous sleep period is doubled, including periods requested by a Retry-After header, limited to the max period.
+func (r *retrierClient) nextSleepPeriod(attempt int, prevSleepPeriod time.Duration) time.Duration {
+	if r.backoff != nil {
+		return r.backoff(attempt, r.minPeriod, r.maxPeriod)
+	}
+	if prevSleepPeriod == 0 {
+		return r.minPeriod
+	}
+	return min(prevSleepPeriod*2, r.maxPeriod)
+}
+
+// ExponentialBackoff is a [RetrierBackoffFunc] that doubles the sleep period on each attempt, starting at base,
+// limited to max. It matches the default backoff, except that the default also doubles sleep periods requested
+// by a Retry-After header.
+func ExponentialBackoff(attempt int, base, max time.Duration) time.Duration {
+	period := base
+	for i := 1; i < attempt && period < max; i++ {
+		period *= 2
+	}
+	return min(period, max)
+}
+
+// LinearBackoff is a [RetrierBackoffFunc] that increases the sleep period by base on each attempt, limited to max.
+func LinearBackoff(attempt int, base, max time.Duration) time.Duration {
+	if base > 0 && time.Duration(attempt) > max/base {
+		return max
+	}
+	return min(time.Duration(attempt)*base, max)
+}
+
+// ConstantBackoff is a [RetrierBackoffFunc] that always sleeps base.
+func ConstantBackoff(_ int, base, _ time.Duration) time.Duration {
+	return base
+}
+
 func defaultSleep(ctx context.Context, period time.Duration) {
 	// Guarantee that we won't sleep more than the request context allows
 	sleepCtx, cancel := context.WithTimeout(ctx, period)
@@ -2,6 +2,7 @@ package xhttp
 
 import (
 	"context"
+	"net/http"
 	"time"
 )
 
@@ -83,3 +84,64 @@ func RetrierWithStatuses(statuses ...int) RetrierOption {
 		}
 	}
 }
+
+// RetrierWithShouldRetryStatus configures a callback that decides if responses with error status codes (>= 400)
+// that are not retried by default (or by [RetrierWithStatuses]) should be retried, like a 400 with a response body
+// indicating a transient failure. The response body is read entirely in memory before calling the callback, so it
+// can be read by the callback and it is restored afterwards, the caller always gets the whole response body when
+// the request is not retried. Retried responses follow the same rules as retried status codes (like Retry-After).
+// The callback is called from the same goroutine that called the retrier Do method.
+func RetrierWithShouldRetryStatus(shouldRetry func(res *http.Response) bool) RetrierOption {
+	return func(r *retrierClient) {
+		r.shouldRetry = shouldRetry
+	}
+}
+
+// RetrierWithUserAgent configures the retrier to set the given User-Agent header on all requests (including retries)
+// that don't already have a User-Agent header. Requests created with [NewRequestWithContext] already have
+// a default User-Agent, use [NewRequestWithUserAgent] or [http.NewRequestWithContext] in that case.
+func RetrierWithUserAgent(userAgent string) RetrierOption {
+	return func(r *retrierClient) {
+		r.userAgent = userAgent
+	}
+}
+
+// RetrierWithIdempotencyKey configures the retrier to set an idempotency key on the given header for each request.
+// A new key (an UUID) is generated for each [Client.Do] call and the same key is used on all attempts of that call,
+// allowing servers that honor idempotency keys to safely deduplicate retried requests (like POSTs).
+// Requests that already have the given header are left untouched.
+func RetrierWithIdempotencyKey(header string) RetrierOption {
+	return func(r *retrierClient) {
+		r.idempotencyKey = header
+	}
+}
+
+// RetrierWithBackoffStrategy configures how long the retrier sleeps between retries.
+// The strategy is called before each retry, unless the sleep period is defined by a Retry-After header on the response.
+// If not defined the previous sleep period is doubled, like [ExponentialBackoff] does, but starting from the period
+// requested by the last Retry-After header, if any. See also [LinearBackoff] and [ConstantBackoff].
+func RetrierWithBackoffStrategy(strategy RetrierBackoffFunc) RetrierOption {
+	return func(r *retrierClient) {
+		r.backoff = strategy
+	}
+}
+
+// RetrierWithBearer configures the retrier to set the Authorization header with the given bearer token (see [WithBearer])
+// on each request attempt, overriding any Authorization header set on the request.
+func RetrierWithBearer(token string) RetrierOption {
+	return func(r *retrierClient) {
+		r.auth = func(req *http.Request) {
+			WithBearer(req, token)
+		}
+	}
+}
+
+// RetrierWithBasicAuth configures the retrier to set the Authorization header with the given credentials
+// (see [WithBasicAuth]) on each request attempt, overriding any Authorization header set on the request.
+func RetrierWithBasicAuth(user, pass string) RetrierOption {
+	return func(r *retrierClient) {
+		r.auth = func(req *http.Request) {
+			WithBasicAuth(req, user, pass)
+		}
+	}
+}
@@ -17,7 +17,7 @@ import (
 )
 
 func TestRetrierWithoutPerRequestTimeout(t *testing.T) {
-	// With no per request timeout all requests must use the original request context
+	// With no per request timeout all requests must use the original request context (with the same cancellation)
 	fakeClient := xhttptest.NewClient()
 	// here we test the proper request timeout being set by setting a very small timeout
 	// per try/request and creating a request with no deadline at all, so we can check that the deadline exists
@@ -49,10 +49,14 @@ func TestRetrierWithoutPerRequestTimeout(t *testing.T) {
 		t.Fatalf("got %d requests; want 3", len(requests))
 	}
 
+	// The request context only adds values (like the attempt) to the original context, it has the same cancellation
 	for i, req := range requests {
-		if req.Context() != ctx {
+		if req.Context().Done() != ctx.Done() {
 			t.Errorf("request %d got %v; want %v", i, req.Context(), ctx)
 		}
+		if _, ok := req.Context().Deadline(); ok {
+			t.Errorf("request %d got unexpected deadline on context %v", i, req.Context())
+		}
 	}
 }
 
@@ -452,6 +456,99 @@ func TestRetrierExponentialBackoff(t *testing.T) {
 	}
 }
 
+func TestRetrierExponentialBackoffAfterRetryAfter(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	gotSleepPeriods := []time.Duration{}
+	sleep := func(_ context.Context, period time.Duration) {
+		gotSleepPeriods = append(gotSleepPeriods, period)
+	}
+
+	client := xhttp.NewRetrierClient(fakeClient,
+		xhttp.RetrierWithMinSleepPeriod(time.Second),
+		xhttp.RetrierWithMaxSleepPeriod(20*time.Second),
+		xhttp.RetrierWithSleep(sleep),
+	)
+
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{"5"}},
+	})
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+	})
+	fakeClient.PushError(retryableError())
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+
+	request := newRequest(t, http.MethodGet, "/test", nil)
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("client.Do(%v) failed: %v", request, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v; want %v", res.StatusCode, http.StatusOK)
+	}
+
+	// The default backoff keeps doubling from the period requested by the Retry-After header.
+	assertEqual(t, gotSleepPeriods, []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second})
+}
+
+func TestRetrierWithBackoffStrategy(t *testing.T) {
+	cases := []struct {
+		name     string
+		strategy xhttp.RetrierBackoffFunc
+		want     []time.Duration
+	}{
+		{
+			name:     "exponential",
+			strategy: xhttp.ExponentialBackoff,
+			want:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+		{
+			name:     "linear",
+			strategy: xhttp.LinearBackoff,
+			want:     []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second},
+		},
+		{
+			name:     "constant",
+			strategy: xhttp.ConstantBackoff,
+			want:     []time.Duration{time.Second, time.Second, time.Second, time.Second},
+		},
+		{
+			name: "custom",
+			strategy: func(attempt int, base, max time.Duration) time.Duration {
+				return max - time.Duration(attempt)*base
+			},
+			want: []time.Duration{4 * time.Second, 3 * time.Second, 2 * time.Second, time.Second},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClient := xhttptest.NewClient()
+			gotSleepPeriods := []time.Duration{}
+			client := xhttp.NewRetrierClient(fakeClient,
+				xhttp.RetrierWithMinSleepPeriod(time.Second),
+				xhttp.RetrierWithMaxSleepPeriod(5*time.Second),
+				xhttp.RetrierWithBackoffStrategy(c.strategy),
+				xhttp.RetrierWithSleep(func(_ context.Context, period time.Duration) {
+					gotSleepPeriods = append(gotSleepPeriods, period)
+				}),
+			)
+
+			for range c.want {
+				fakeClient.PushError(retryableError())
+			}
+			fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK})
+
+			if _, err := client.Do(newRequest(t, http.MethodGet, "http://test", nil)); err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, gotSleepPeriods, c.want)
+		})
+	}
+}
+
 func TestRetrierWontRetryIfParentCtxExceeded(t *testing.T) {
 	// Lets guarantee that we don't sleep at all when the parent context is canceled using the default sleep implementation
 	// This test will hang for an hour if the default behavior is broken.
@@ -555,6 +652,8 @@ func TestRetrierRetrySpecificErrors(t *testing.T) {
 		errors.New("<specific details>: use of closed network connection"),
 		errors.New("<specific details>: Temporary failure in name resolution"),
 		context.DeadlineExceeded,
+		fmt.Errorf("%w: custom client error", xhttp.ErrTransient),
+		errors.Join(xhttp.ErrTransient, errors.New("custom client error")),
 	}
 	for _, retryError := range retryErrors {
 		t.Run(retryError.Error(), func(t *testing.T) {
@@ -602,6 +701,230 @@ func TestWontRetryClientErrors(t *testing.T) {
 	}
 }
 
+func TestRetrierRetryErrorHistory(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewRetrierClient(fakeClient, noSleep())
+
+	transientErr := retryableError()
+	wantErr := errors.New("fatal error")
+	fakeClient.PushError(transientErr)
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(strings.NewReader("")),
+	})
+	fakeClient.PushError(wantErr)
+
+	_, err := client.Do(newRequest(t, http.MethodGet, "http://test", nil))
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("got err %v; want %v", err, wantErr)
+	}
+
+	var retryErr *xhttp.RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("got err %T; want %T", err, retryErr)
+	}
+	assertEqual(t, len(retryErr.Attempts), 3)
+
+	var (
+		gotStatusCodes []int
+		gotErrs        []error
+	)
+	for _, attempt := range retryErr.Attempts {
+		if attempt.Time.IsZero() {
+			t.Errorf("attempt %+v has no time", attempt)
+		}
+		gotStatusCodes = append(gotStatusCodes, attempt.StatusCode)
+		gotErrs = append(gotErrs, attempt.Err)
+	}
+	assertEqual(t, gotStatusCodes, []int{0, http.StatusServiceUnavailable, 0})
+	if gotErrs[0] != transientErr || gotErrs[1] != nil || gotErrs[2] != wantErr {
+		t.Fatalf("got attempt errors %v; want [%v <nil> %v]", gotErrs, transientErr, wantErr)
+	}
+}
+
+func TestRetrierAttemptFromContext(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		t.Run(fmt.Sprintf("timeout %v", timeout), func(t *testing.T) {
+			fakeClient := xhttptest.NewClient()
+			client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithRequestTimeout(timeout))
+
+			fakeClient.PushError(retryableError())
+			fakeClient.PushResponse(&http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("")),
+			})
+			fakeClient.PushResponse(&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("")),
+			})
+
+			request := newRequest(t, http.MethodGet, "http://test", nil)
+			if _, err := client.Do(request); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			for _, req := range fakeClient.Requests() {
+				got = append(got, xhttp.AttemptFromContext(req.Context()))
+			}
+			assertEqual(t, got, []int{0, 1, 2})
+			assertEqual(t, xhttp.AttemptFromContext(request.Context()), 0)
+		})
+	}
+}
+
+func TestRetrierWithUserAgent(t *testing.T) {
+	const wantUserAgent = "test-agent/1.0"
+
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithUserAgent(wantUserAgent))
+
+	fakeClient.PushError(retryableError())
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+
+	request := newRequest(t, http.MethodGet, "http://test", nil)
+	if _, err := client.Do(request); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fakeClient.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests; want 2", len(requests))
+	}
+	for i, req := range requests {
+		if got := req.UserAgent(); got != wantUserAgent {
+			t.Errorf("request %d: got user agent %q; want %q", i, got, wantUserAgent)
+		}
+	}
+	if got := request.Header.Get("User-Agent"); got != "" {
+		t.Errorf("original request user agent changed to %q", got)
+	}
+
+	// Requests that already have an user agent are left untouched
+	const requestUserAgent = "request-agent/2.0"
+
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+	request = newRequest(t, http.MethodGet, "http://test", nil)
+	request.Header.Set("User-Agent", requestUserAgent)
+	if _, err := client.Do(request); err != nil {
+		t.Fatal(err)
+	}
+	requests = fakeClient.Requests()
+	if got := requests[len(requests)-1].UserAgent(); got != requestUserAgent {
+		t.Errorf("got user agent %q; want %q", got, requestUserAgent)
+	}
+}
+
+func TestRetrierWithAuth(t *testing.T) {
+	cases := []struct {
+		name   string
+		option xhttp.RetrierOption
+		want   func(*http.Request) bool
+	}{
+		{
+			name:   "bearer",
+			option: xhttp.RetrierWithBearer("token"),
+			want: func(req *http.Request) bool {
+				return req.Header.Get("Authorization") == "Bearer token"
+			},
+		},
+		{
+			name:   "basic",
+			option: xhttp.RetrierWithBasicAuth("user", "pass"),
+			want: func(req *http.Request) bool {
+				user, pass, ok := req.BasicAuth()
+				return ok && user == "user" && pass == "pass"
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClient := xhttptest.NewClient()
+			client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithRequestTimeout(time.Minute), c.option)
+
+			fakeClient.PushError(retryableError())
+			fakeClient.PushResponse(&http.Response{
+				StatusCode: http.StatusOK,
+			})
+
+			request := newRequest(t, http.MethodGet, "http://test", nil)
+			if _, err := client.Do(request); err != nil {
+				t.Fatal(err)
+			}
+
+			requests := fakeClient.Requests()
+			assertEqual(t, len(requests), 2)
+			for i, req := range requests {
+				if !c.want(req) {
+					t.Errorf("request %d: unexpected Authorization header %q", i, req.Header.Get("Authorization"))
+				}
+			}
+			if got := request.Header.Get("Authorization"); got != "" {
+				t.Errorf("original request Authorization header changed to %q", got)
+			}
+		})
+	}
+}
+
+func TestRetrierWithIdempotencyKey(t *testing.T) {
+	const header = "Idempotency-Key"
+
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithIdempotencyKey(header))
+
+	fakeClient.PushError(retryableError())
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+	})
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+
+	if _, err := client.Do(newRequest(t, http.MethodPost, "http://test", []byte("body"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(newRequest(t, http.MethodPost, "http://test", []byte("body"))); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := fakeClient.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests; want 4", len(requests))
+	}
+
+	key := requests[0].Header.Get(header)
+	if key == "" {
+		t.Fatal("want idempotency key, got none")
+	}
+	for i, req := range requests[:3] {
+		if got := req.Header.Get(header); got != key {
+			t.Errorf("request %d: got idempotency key %q; want %q", i, got, key)
+		}
+	}
+	if got := requests[3].Header.Get(header); got == "" || got == key {
+		t.Errorf("got idempotency key %q; want a new key different from %q", got, key)
+	}
+
+	// Keys provided by the caller are respected
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+	})
+	request := newRequest(t, http.MethodPost, "http://test", nil)
+	request.Header.Set(header, "caller-key")
+	if _, err := client.Do(request); err != nil {
+		t.Fatal(err)
+	}
+	requests = fakeClient.Requests()
+	assertEqual(t, requests[len(requests)-1].Header.Get(header), "caller-key")
+}
+
 func TestRetrierRetryStatusCodes(t *testing.T) {
 	// Default status codes that are always retried
 	retryStatusCodes := []int{
@@ -845,6 +1168,67 @@ func (f *fakeReaderCloser) Close() error {
 	return f.closeErr
 }
 
+func TestRetrierWithShouldRetryStatus(t *testing.T) {
+	const (
+		transientBody = `{"code":"transient"}`
+		finalBody     = `{"code":"invalid"}`
+	)
+	fakeClient := xhttptest.NewClient()
+	var calls []int
+	client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithShouldRetryStatus(func(res *http.Response) bool {
+		calls = append(calls, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body) == transientBody
+	}))
+
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(transientBody))})
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(""))})
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusConflict, Body: io.NopCloser(strings.NewReader(transientBody))})
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(finalBody))})
+
+	res, err := client.Do(newRequest(t, http.MethodPost, "http://testing", []byte("data")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	assertEqual(t, res.StatusCode, http.StatusBadRequest)
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(body), finalBody)
+	assertEqual(t, len(fakeClient.Requests()), 4)
+	// Statuses that are retried by default don't call the callback
+	assertEqual(t, calls, []int{http.StatusBadRequest, http.StatusConflict, http.StatusBadRequest})
+}
+
+func TestRetrierWithShouldRetryStatusIgnoresSuccess(t *testing.T) {
+	fakeClient := xhttptest.NewClient()
+	client := xhttp.NewRetrierClient(fakeClient, noSleep(), xhttp.RetrierWithShouldRetryStatus(func(*http.Response) bool {
+		t.Fatal("callback should not be called for successful responses")
+		return true
+	}))
+
+	body := watchClose(strings.NewReader("ok"))
+	fakeClient.PushResponse(&http.Response{StatusCode: http.StatusOK, Body: body})
+
+	res, err := client.Do(newRequest(t, http.MethodGet, "http://testing", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.StatusCode, http.StatusOK)
+	// Successful responses are not buffered
+	assertEqual(t, body.CloseCalls, 0)
+}
+
 func newRequest(t *testing.T, method, url string, body []byte) *http.Request {
 	t.Helper()
 
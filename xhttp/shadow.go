@@ -0,0 +1,106 @@
+package xhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+// ShadowCompareFunc is the callback called by clients created with [NewShadowClient].
+// The primary response is the one returned to the caller, the shadow response is the one received from the shadow client.
+// Both response bodies can be read by the callback, the shadow response body is closed after the callback returns.
+type ShadowCompareFunc func(primaryRes, shadowRes *http.Response)
+
+// NewShadowClient creates a [Client] that sends all requests to the primary client and mirrors them to the shadow client.
+// The response (or error) of the primary client is returned to the caller, the request to the shadow client is sent
+// asynchronously and when both requests succeed the given compare function is called (from another goroutine) with both responses.
+//
+// Failures of the shadow client never affect the primary path, they are only logged.
+// The shadow request context is not cancelled when the primary request is done, but it keeps all its values.
+// The request body and the primary response body are read entirely in memory, so they can be sent/read twice.
+func NewShadowClient(primary, shadow Client, compare ShadowCompareFunc) Client {
+	return &shadowClient{
+		primary: primary,
+		shadow:  shadow,
+		compare: compare,
+	}
+}
+
+type shadowClient struct {
+	primary Client
+	shadow  Client
+	compare ShadowCompareFunc
+}
+
+func (s *shadowClient) Do(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, fmt.Errorf("closing request body: %w", err)
+		}
+	}
+
+	shadowReq := req.Clone(context.WithoutCancel(req.Context()))
+	shadowReq.Body = io.NopCloser(bytes.NewReader(requestBody))
+	shadowRes := make(chan *http.Response, 1)
+
+	go func() {
+		log := slog.FromCtx(shadowReq.Context()).With("request_url", shadowReq.URL)
+		res, err := s.shadow.Do(shadowReq)
+		if err != nil {
+			log.Debug("xhttp.ShadowClient: shadow request failed", "error", err)
+			close(shadowRes)
+			return
+		}
+		shadowRes <- res
+	}()
+
+	req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	res, err := s.primary.Do(req)
+	if err != nil {
+		go discardShadowResponse(shadowReq.Context(), shadowRes)
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(res.Body)
+	if cerr := res.Body.Close(); cerr != nil {
+		slog.FromCtx(req.Context()).Debug("xhttp.ShadowClient: error closing primary response body", "error", cerr)
+	}
+	if err != nil {
+		go discardShadowResponse(shadowReq.Context(), shadowRes)
+		return nil, fmt.Errorf("reading primary response body: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	// The compare function runs concurrently with the caller, so it gets its own headers
+	primaryRes := *res
+	primaryRes.Header = res.Header.Clone()
+	primaryRes.Trailer = res.Trailer.Clone()
+	primaryRes.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	go func() {
+		shadowRes, ok := <-shadowRes
+		if !ok {
+			return
+		}
+		defer closeBody(shadowReq.Context(), shadowRes.Body)
+		s.compare(&primaryRes, shadowRes)
+	}()
+
+	return res, nil
+}
+
+func discardShadowResponse(ctx context.Context, shadowRes <-chan *http.Response) {
+	if res, ok := <-shadowRes; ok {
+		closeBody(ctx, res.Body)
+	}
+}
@@ -0,0 +1,107 @@
+package xhttp_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestShadowClient(t *testing.T) {
+	primary := xhttptest.NewClient()
+	shadow := xhttptest.NewClient()
+
+	primary.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-Test": []string{"primary"}},
+		Body:       io.NopCloser(strings.NewReader("primary")),
+	})
+	// The shadow request only finishes after the caller changed the primary response
+	release := make(chan struct{})
+	shadow.OnDo(func(*http.Request) {
+		<-release
+	})
+	shadow.PushResponse(&http.Response{
+		StatusCode: http.StatusCreated,
+		Body:       io.NopCloser(strings.NewReader("shadow")),
+	})
+
+	type comparison struct {
+		PrimaryStatus, ShadowStatus int
+		PrimaryBody, ShadowBody     string
+		PrimaryHeader               string
+	}
+	compared := make(chan comparison)
+	client := xhttp.NewShadowClient(primary, shadow, func(primaryRes, shadowRes *http.Response) {
+		primaryBody, _ := io.ReadAll(primaryRes.Body)
+		shadowBody, _ := io.ReadAll(shadowRes.Body)
+		compared <- comparison{
+			PrimaryStatus: primaryRes.StatusCode,
+			ShadowStatus:  shadowRes.StatusCode,
+			PrimaryBody:   string(primaryBody),
+			ShadowBody:    string(shadowBody),
+			PrimaryHeader: primaryRes.Header.Get("X-Test"),
+		}
+	})
+
+	res, err := client.Do(newRequest(t, http.MethodPost, "http://test", []byte("request")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.StatusCode, http.StatusOK)
+	assertEqual(t, string(body), "primary")
+	res.Header.Set("X-Test", "changed")
+	close(release)
+
+	assertEqual(t, <-compared, comparison{
+		PrimaryStatus: http.StatusOK,
+		ShadowStatus:  http.StatusCreated,
+		PrimaryBody:   "primary",
+		ShadowBody:    "shadow",
+		PrimaryHeader: "primary",
+	})
+
+	for name, c := range map[string]*xhttptest.Client{"primary": primary, "shadow": shadow} {
+		requests := c.Requests()
+		if len(requests) != 1 {
+			t.Fatalf("%s: got %d requests; want 1", name, len(requests))
+		}
+		reqBody, err := io.ReadAll(requests[0].Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, string(reqBody), "request")
+	}
+}
+
+func TestShadowClientShadowFailure(t *testing.T) {
+	primary := xhttptest.NewClient()
+	shadow := xhttptest.NewClient()
+
+	primary.PushResponse(&http.Response{StatusCode: http.StatusOK})
+	shadow.PushError(errors.New("shadow error"))
+
+	shadowCalled := make(chan struct{})
+	shadow.OnDo(func(*http.Request) {
+		close(shadowCalled)
+	})
+
+	client := xhttp.NewShadowClient(primary, shadow, func(*http.Response, *http.Response) {
+		t.Error("compare should not be called on shadow failure")
+	})
+
+	res, err := client.Do(newRequest(t, http.MethodGet, "http://test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.StatusCode, http.StatusOK)
+	<-shadowCalled
+}
@@ -0,0 +1,102 @@
+package xhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/birdie-ai/golibs/slog"
+)
+
+// SSE sends the given request using the given client and reads the response as a [Server-Sent Events] stream.
+// The data of each event is decoded as JSON into [T].
+// The returned function is an iterator (compatible with iter.Seq2[T, error]) that yields each decoded event.
+// If an event can't be decoded or reading the stream fails the error is yielded and the iteration stops.
+//
+// The response body is closed when the iteration is done, stopped by the caller or [ctx] is cancelled.
+// The caller must always iterate (even if only partially) to guarantee that the response body is closed.
+// If the response has a non 2xx status code the response body is closed and an error is returned.
+//
+// Only the "data" field of events is used, multi-line data fields are joined with a new line as defined by the spec.
+// Events with no data are ignored. Reconnecting (and the "id"/"retry" fields) is not supported.
+//
+// [Server-Sent Events]: https://html.spec.whatwg.org/multipage/server-sent-events.html
+func SSE[T any](ctx context.Context, c Client, req *http.Request) (func(yield func(T, error) bool), error) {
+	req = req.WithContext(ctx)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("xhttp.SSE: sending request: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxDrainSize))
+		closeBody(ctx, res.Body)
+		return nil, fmt.Errorf("xhttp.SSE: unexpected status %d: %s", res.StatusCode, string(body))
+	}
+
+	return func(yield func(T, error) bool) {
+		defer closeBody(ctx, res.Body)
+
+		reader := bufio.NewReader(res.Body)
+		var data []string
+
+		dispatch := func() bool {
+			if len(data) == 0 {
+				return true
+			}
+			var event T
+			err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event)
+			data = data[:0]
+			if err != nil {
+				yield(event, fmt.Errorf("xhttp.SSE: decoding event: %w", err))
+				return false
+			}
+			return yield(event, nil)
+		}
+
+		for {
+			line, err := reader.ReadString('\n')
+			if errors.Is(err, io.EOF) {
+				// As defined by the spec, an incomplete event at the end of the stream is discarded.
+				return
+			}
+			if err != nil {
+				var zero T
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				yield(zero, fmt.Errorf("xhttp.SSE: reading stream: %w", err))
+				return
+			}
+
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			switch {
+			case line == "":
+				if !dispatch() {
+					return
+				}
+			case strings.HasPrefix(line, ":"):
+				// comment, ignored
+			default:
+				field, value, _ := strings.Cut(line, ":")
+				if field == "data" {
+					data = append(data, strings.TrimPrefix(value, " "))
+				}
+			}
+		}
+	}, nil
+}
+
+func closeBody(ctx context.Context, body io.Closer) {
+	if err := body.Close(); err != nil {
+		slog.FromCtx(ctx).Debug("xhttp: unable to close response body", "error", err)
+	}
+}
@@ -0,0 +1,152 @@
+package xhttp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/birdie-ai/golibs/xhttp"
+	"github.com/birdie-ai/golibs/xhttptest"
+)
+
+func TestSSE(t *testing.T) {
+	type Event struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}
+
+	const stream = ": comment\n" +
+		"event: update\n" +
+		"data: {\"id\": 1, \"text\": \"first\"}\n" +
+		"\n" +
+		"data: {\"id\": 2,\r\n" +
+		"data:\"text\": \"second\"}\r\n" +
+		"\r\n" +
+		"id: no data\n" +
+		"\n" +
+		"data: {\"id\": 3, \"text\": \"incomplete\"}\n"
+
+	body := watchClose(strings.NewReader(stream))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       body,
+	})
+
+	ctx := context.Background()
+	events, err := xhttp.SSE[Event](ctx, fakeClient, newRequest(t, http.MethodGet, "http://test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Event
+	events(func(e Event, err error) bool {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+		return true
+	})
+
+	assertEqual(t, got, []Event{{ID: 1, Text: "first"}, {ID: 2, Text: "second"}})
+	assertEqual(t, body.CloseCalls, 1)
+
+	requests := fakeClient.Requests()
+	assertEqual(t, requests[0].Header.Get("Accept"), "text/event-stream")
+}
+
+func TestSSEStopIteration(t *testing.T) {
+	const stream = "data: 1\n\ndata: 2\n\n"
+
+	body := watchClose(strings.NewReader(stream))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       body,
+	})
+
+	events, err := xhttp.SSE[int](context.Background(), fakeClient, newRequest(t, http.MethodGet, "http://test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	events(func(v int, err error) bool {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+		return false
+	})
+
+	assertEqual(t, got, []int{1})
+	assertEqual(t, body.CloseCalls, 1)
+}
+
+func TestSSEDecodeError(t *testing.T) {
+	const stream = "data: 1\n\ndata: not json\n\ndata: 3\n\n"
+
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(stream)),
+	})
+
+	events, err := xhttp.SSE[int](context.Background(), fakeClient, newRequest(t, http.MethodGet, "http://test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		got  []int
+		errs []error
+	)
+	events(func(v int, err error) bool {
+		if err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		got = append(got, v)
+		return true
+	})
+
+	assertEqual(t, got, []int{1})
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v; want 1 error", errs)
+	}
+}
+
+func TestSSEErrorStatus(t *testing.T) {
+	body := watchClose(strings.NewReader("error"))
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       body,
+	})
+
+	_, err := xhttp.SSE[int](context.Background(), fakeClient, newRequest(t, http.MethodGet, "http://test", nil))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	assertEqual(t, body.CloseCalls, 1)
+}
+
+func TestSSEErrorStatusLimitsBody(t *testing.T) {
+	const bodySize = 1 << 20
+	fakeClient := xhttptest.NewClient()
+	fakeClient.PushResponse(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       io.NopCloser(strings.NewReader(strings.Repeat("a", bodySize))),
+	})
+
+	_, err := xhttp.SSE[int](context.Background(), fakeClient, newRequest(t, http.MethodGet, "http://test", nil))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	// Only the beginning of big error bodies is read (and added to the error)
+	if len(err.Error()) >= bodySize {
+		t.Fatalf("got error with %d bytes; want the body to be truncated", len(err.Error()))
+	}
+}
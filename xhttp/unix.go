@@ -0,0 +1,39 @@
+package xhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ClientOption is used to configure clients created with [NewUnixSocketClient].
+type ClientOption func(*http.Client)
+
+// NewUnixSocketClient creates a [Client] that sends all requests to the HTTP server listening on the given
+// unix domain socket, like sidecars and local agents that don't listen on TCP.
+// Requests still use normal http URLs, like "http://localhost/path". The host of the URL is ignored when connecting,
+// all connections are made to the socket, but it is still sent as the Host header (so servers that check it still work).
+// The client has the same configuration as [http.DefaultTransport] (except for proxies, which are never used) and
+// can be wrapped by other clients, like [NewRetrierClient].
+func NewUnixSocketClient(socketPath string, options ...ClientOption) Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+	client := &http.Client{Transport: transport}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// ClientWithTimeout configures the overall timeout of each request, see [http.Client] Timeout.
+// If not defined requests have no timeout (besides their context).
+func ClientWithTimeout(timeout time.Duration) ClientOption {
+	return func(c *http.Client) {
+		c.Timeout = timeout
+	}
+}
@@ -0,0 +1,77 @@
+package xhttp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/birdie-ai/golibs/xhttp"
+)
+
+func TestUnixSocketClient(t *testing.T) {
+	// Unix socket paths have a small max length, so t.TempDir can't be used (it is based on the test name)
+	dir, err := os.MkdirTemp("", "xhttp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	socketPath := filepath.Join(dir, "server.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.WriteString(w, req.Host+req.URL.Path)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("serving: %v", err)
+		}
+	}()
+	defer func() {
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	client := xhttp.NewUnixSocketClient(socketPath, xhttp.ClientWithTimeout(10*time.Second))
+	res, err := client.Do(newRequest(t, http.MethodGet, "http://agent/path", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, res.StatusCode, http.StatusOK)
+	assertEqual(t, string(body), "agent/path")
+}
+
+func TestUnixSocketClientNoServer(t *testing.T) {
+	client := xhttp.NewUnixSocketClient(filepath.Join(t.TempDir(), "missing.sock"))
+	res, err := client.Do(newRequest(t, http.MethodGet, "http://agent/path", nil))
+	if err == nil {
+		_ = res.Body.Close()
+		t.Fatal("want error when socket doesn't exist, got nil")
+	}
+}
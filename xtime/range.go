@@ -2,7 +2,10 @@
 package xtime
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/bits"
+	"strings"
 	"time"
 )
 
@@ -48,6 +51,85 @@ func (r Range) Split(max time.Duration) []Range {
 	return result
 }
 
+// SplitN returns a list of exactly [n] contiguous time ranges that together make up [r].
+// The ranges have equal duration, except when the duration of [r] is not divisible by [n], in that case
+// the remainder is distributed by adding a nanosecond to the first ranges, so the ranges always tile [r] exactly.
+// It returns an error if [n] <= 0.
+func (r Range) SplitN(n int) ([]Range, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("splitting range in %d: n must be > 0", n)
+	}
+	total := r.Duration()
+	size := total / time.Duration(n)
+	remainder := total % time.Duration(n)
+
+	result := make([]Range, n)
+	start := r.start
+	for i := range result {
+		end := start.Add(size)
+		if time.Duration(i) < remainder {
+			end = end.Add(1)
+		}
+		result[i] = Range{start: start, end: end}
+		start = end
+	}
+	return result, nil
+}
+
+// CalendarUnit is a unit of calendar time, like a day or a month, used by [Range.Buckets].
+// Unlike a [time.Duration] its length varies, like months with different number of days or days with DST changes.
+type CalendarUnit int
+
+// All available calendar units.
+const (
+	CalendarDay CalendarUnit = iota
+	// CalendarWeek weeks start on Monday (ISO 8601).
+	CalendarWeek
+	CalendarMonth
+	CalendarYear
+)
+
+// Buckets returns a list of contiguous time ranges, aligned to the boundaries of [unit] on the calendar of [loc],
+// that together make up [r]. Like the days of [r] on a specific timezone, where each day starts at midnight.
+// The first and last ranges are clamped to [r], so they may be partial buckets.
+// The returned times are on [loc]. If [r] is empty it returns [r] as the only range (like [Range.Split]).
+func (r Range) Buckets(unit CalendarUnit, loc *time.Location) []Range {
+	start, end := r.start.In(loc), r.end.In(loc)
+	if !start.Before(end) {
+		return []Range{{start: start, end: end}}
+	}
+
+	var result []Range
+	bucketStart := calendarFloor(start, unit)
+	for bucketStart.Before(end) {
+		bucketEnd := calendarAdd(bucketStart, unit)
+		result = append(result, Range{
+			start: latest(bucketStart, start),
+			end:   earliest(bucketEnd, end),
+		})
+		bucketStart = bucketEnd
+	}
+	return result
+}
+
+// Round returns a new range with the start rounded down and the end rounded up to multiples of [d].
+// The multiples are anchored on the Unix epoch (January 1, 1970 UTC), so rounding to an hour or a day
+// aligns to UTC hour/day boundaries. The returned range always contains [r].
+// If [d] <= 0 the range is returned unchanged.
+func (r Range) Round(d time.Duration) Range {
+	if d <= 0 {
+		return r
+	}
+	end := floor(r.end, d)
+	if !end.Equal(r.end) {
+		end = end.Add(d)
+	}
+	return Range{
+		start: floor(r.start, d),
+		end:   end,
+	}
+}
+
 // NewRange creates a new [Range] validating start/end.
 // It ensures the invariant that [Range] always has start <= end.
 func NewRange(start, end time.Time) (Range, error) {
@@ -59,3 +141,123 @@ func NewRange(start, end time.Time) (Range, error) {
 	}
 	return Range{start, end}, nil
 }
+
+// rangeJSON is the JSON representation of a [Range].
+type rangeJSON struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// MarshalJSON encodes the range as a JSON object like {"start": "2024-01-01T00:00:00Z", "end": "2024-01-02T00:00:00Z"},
+// with start and end formatted as RFC3339 (with nanoseconds, if any).
+func (r Range) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rangeJSON{
+		Start: r.start.Format(time.RFC3339Nano),
+		End:   r.end.Format(time.RFC3339Nano),
+	})
+}
+
+// UnmarshalJSON decodes a range encoded by [Range.MarshalJSON].
+// The range is validated like in [NewRange], so invalid ranges (like inverted or zero) fail.
+func (r *Range) UnmarshalJSON(data []byte) error {
+	var v rangeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("decoding range: %w", err)
+	}
+	return r.parse(v.Start, v.End)
+}
+
+// MarshalText encodes the range as an ISO 8601 time interval, like "2024-01-01T00:00:00Z/2024-01-02T00:00:00Z",
+// with start and end formatted as RFC3339 (with nanoseconds, if any). Useful for map keys and query parameters.
+func (r Range) MarshalText() ([]byte, error) {
+	return []byte(r.start.Format(time.RFC3339Nano) + "/" + r.end.Format(time.RFC3339Nano)), nil
+}
+
+// UnmarshalText decodes a range encoded by [Range.MarshalText].
+// The range is validated like in [NewRange], so invalid ranges (like inverted or zero) fail.
+func (r *Range) UnmarshalText(text []byte) error {
+	start, end, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("decoding range %q: want start/end", text)
+	}
+	return r.parse(start, end)
+}
+
+func (r *Range) parse(start, end string) error {
+	s, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return fmt.Errorf("decoding range start: %w", err)
+	}
+	e, err := time.Parse(time.RFC3339Nano, end)
+	if err != nil {
+		return fmt.Errorf("decoding range end: %w", err)
+	}
+	parsed, err := NewRange(s, e)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// floor rounds [t] down to a multiple of [d] (which must be positive) since the Unix epoch.
+// The remainder is computed from the Unix seconds and nanoseconds of [t], since the duration since
+// the epoch (t.Sub) saturates for times more than ~292 years away from it.
+func floor(t time.Time, d time.Duration) time.Time {
+	secRem := t.Unix() % int64(d)
+	if secRem < 0 {
+		secRem += int64(d)
+	}
+	// (secRem * 1e9 + nsec) % d, with 128 bits since it may not fit 64 bits.
+	hi, lo := bits.Mul64(uint64(secRem), uint64(time.Second))
+	lo, carry := bits.Add64(lo, uint64(t.Nanosecond()), 0)
+	rem := bits.Rem64(hi+carry, lo, uint64(d))
+	return t.Add(-time.Duration(rem))
+}
+
+// calendarFloor returns the start of the calendar [unit] that contains [t], on the location of [t].
+func calendarFloor(t time.Time, unit CalendarUnit) time.Time {
+	year, month, day := t.Date()
+	switch unit {
+	case CalendarWeek:
+		// Go weekdays start on Sunday (0), ISO weeks on Monday.
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	case CalendarMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case CalendarYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// calendarAdd returns the start of the calendar [unit] after the one that starts at [t], on the location of [t].
+// It uses dates instead of durations, so it is always midnight even across DST changes.
+func calendarAdd(t time.Time, unit CalendarUnit) time.Time {
+	year, month, day := t.Date()
+	switch unit {
+	case CalendarWeek:
+		return time.Date(year, month, day+7, 0, 0, 0, 0, t.Location())
+	case CalendarMonth:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+	case CalendarYear:
+		return time.Date(year+1, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package xtime_test
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -131,6 +132,299 @@ func TestRangeSplit(t *testing.T) {
 	}
 }
 
+func TestRangeSplitN(t *testing.T) {
+	cases := []struct {
+		from, to time.Time
+		n        int
+		want     []xtime.Range
+	}{
+		{
+			tm(1, 0),
+			tm(2, 0),
+			1,
+			[]xtime.Range{newRange(tm(1, 0), tm(2, 0))},
+		},
+		{
+			tm(1, 0),
+			tm(2, 0),
+			3,
+			[]xtime.Range{newRange(tm(1, 0), tm(1, 20)), newRange(tm(1, 20), tm(1, 40)), newRange(tm(1, 40), tm(2, 0))},
+		},
+		{
+			tm(1, 0),
+			tm(1, 0).Add(5),
+			2,
+			[]xtime.Range{newRange(tm(1, 0), tm(1, 0).Add(3)), newRange(tm(1, 0).Add(3), tm(1, 0).Add(5))},
+		},
+		{
+			tm(1, 0),
+			tm(1, 0),
+			2,
+			[]xtime.Range{newRange(tm(1, 0), tm(1, 0)), newRange(tm(1, 0), tm(1, 0))},
+		},
+	}
+	comparer := cmp.Comparer(func(a xtime.Range, b xtime.Range) bool {
+		return (a.Start() == b.Start()) && (a.End() == b.End())
+	})
+	for _, c := range cases {
+		got, err := newRange(c.from, c.to).SplitN(c.n)
+		if err != nil {
+			t.Fatalf("xtime.Range{%v, %v}.SplitN(%d) returned error: %v", c.from, c.to, c.n, err)
+		}
+		if diff := cmp.Diff(c.want, got, comparer); diff != "" {
+			t.Errorf("splitN xtime.Range mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := newRange(tm(1, 0), tm(2, 0)).SplitN(n); err == nil {
+			t.Errorf("SplitN(%d) did not return error", n)
+		}
+	}
+}
+
+func TestRangeRound(t *testing.T) {
+	cases := []struct {
+		start, end         time.Time
+		d                  time.Duration
+		wantStart, wantEnd time.Time
+	}{
+		{tm(1, 0), tm(2, 0), time.Hour, tm(1, 0), tm(2, 0)},
+		{tm(1, 10), tm(1, 50), time.Hour, tm(1, 0), tm(2, 0)},
+		{tm(1, 10), tm(1, 10), time.Hour, tm(1, 0), tm(2, 0)},
+		{tm(1, 10), tm(2, 10), 30 * time.Minute, tm(1, 0), tm(2, 30)},
+		{tm(1, 10), tm(2, 10), 24 * time.Hour, tm(0, 0), tm(24, 0)},
+		{tm(1, 10), tm(2, 10), 0, tm(1, 10), tm(2, 10)},
+		{tm(1, 10), tm(2, 10), -time.Hour, tm(1, 10), tm(2, 10)},
+		{
+			time.Date(1969, 12, 31, 23, 10, 0, 0, time.UTC),
+			time.Date(1969, 12, 31, 23, 20, 0, 0, time.UTC),
+			time.Hour,
+			time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC),
+			time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		// More than ~292 years from the Unix epoch, the duration since it doesn't fit a time.Duration
+		{
+			time.Date(2500, 6, 1, 10, 10, 0, 0, time.UTC),
+			time.Date(2500, 6, 1, 10, 20, 0, 0, time.UTC),
+			time.Hour,
+			time.Date(2500, 6, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2500, 6, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			time.Date(1500, 6, 1, 10, 10, 0, 5, time.UTC),
+			time.Date(1500, 6, 1, 10, 20, 0, 0, time.UTC),
+			time.Minute,
+			time.Date(1500, 6, 1, 10, 10, 0, 0, time.UTC),
+			time.Date(1500, 6, 1, 10, 20, 0, 0, time.UTC),
+		},
+		{
+			time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC),
+			time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC),
+			time.Millisecond,
+			time.Date(9999, 12, 31, 23, 59, 59, 999000000, time.UTC),
+			time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range cases {
+		got := newRange(c.start, c.end).Round(c.d)
+		if !got.Start().Equal(c.wantStart) || !got.End().Equal(c.wantEnd) {
+			t.Errorf("xtime.Range{%v, %v}.Round(%v) == {%v, %v}, want {%v, %v}",
+				c.start, c.end, c.d, got.Start(), got.End(), c.wantStart, c.wantEnd)
+		}
+	}
+}
+
+func TestRangeBuckets(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	date := func(year int, month time.Month, day, hour int, loc *time.Location) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name       string
+		start, end time.Time
+		unit       xtime.CalendarUnit
+		loc        *time.Location
+		want       [][2]time.Time
+	}{
+		{
+			name:  "partial days",
+			start: date(2023, 1, 1, 10, time.UTC),
+			end:   date(2023, 1, 3, 5, time.UTC),
+			unit:  xtime.CalendarDay,
+			loc:   time.UTC,
+			want: [][2]time.Time{
+				{date(2023, 1, 1, 10, time.UTC), date(2023, 1, 2, 0, time.UTC)},
+				{date(2023, 1, 2, 0, time.UTC), date(2023, 1, 3, 0, time.UTC)},
+				{date(2023, 1, 3, 0, time.UTC), date(2023, 1, 3, 5, time.UTC)},
+			},
+		},
+		{
+			name:  "days on timezone",
+			start: date(2023, 1, 1, 0, time.UTC),
+			end:   date(2023, 1, 2, 0, time.UTC),
+			unit:  xtime.CalendarDay,
+			loc:   saoPaulo,
+			want: [][2]time.Time{
+				{date(2022, 12, 31, 21, saoPaulo), date(2023, 1, 1, 0, saoPaulo)},
+				{date(2023, 1, 1, 0, saoPaulo), date(2023, 1, 1, 21, saoPaulo)},
+			},
+		},
+		{
+			name:  "days with DST change",
+			start: date(2023, 3, 12, 0, newYork),
+			end:   date(2023, 3, 14, 0, newYork),
+			unit:  xtime.CalendarDay,
+			loc:   newYork,
+			want: [][2]time.Time{
+				{date(2023, 3, 12, 0, newYork), date(2023, 3, 13, 0, newYork)},
+				{date(2023, 3, 13, 0, newYork), date(2023, 3, 14, 0, newYork)},
+			},
+		},
+		{
+			name:  "weeks start on monday",
+			start: date(2023, 1, 4, 0, time.UTC),
+			end:   date(2023, 1, 16, 0, time.UTC),
+			unit:  xtime.CalendarWeek,
+			loc:   time.UTC,
+			want: [][2]time.Time{
+				{date(2023, 1, 4, 0, time.UTC), date(2023, 1, 9, 0, time.UTC)},
+				{date(2023, 1, 9, 0, time.UTC), date(2023, 1, 16, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "months",
+			start: date(2023, 1, 15, 0, time.UTC),
+			end:   date(2023, 3, 2, 0, time.UTC),
+			unit:  xtime.CalendarMonth,
+			loc:   time.UTC,
+			want: [][2]time.Time{
+				{date(2023, 1, 15, 0, time.UTC), date(2023, 2, 1, 0, time.UTC)},
+				{date(2023, 2, 1, 0, time.UTC), date(2023, 3, 1, 0, time.UTC)},
+				{date(2023, 3, 1, 0, time.UTC), date(2023, 3, 2, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "years",
+			start: date(2023, 6, 1, 0, time.UTC),
+			end:   date(2024, 1, 1, 0, time.UTC),
+			unit:  xtime.CalendarYear,
+			loc:   time.UTC,
+			want: [][2]time.Time{
+				{date(2023, 6, 1, 0, time.UTC), date(2024, 1, 1, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "empty range",
+			start: date(2023, 6, 1, 0, time.UTC),
+			end:   date(2023, 6, 1, 0, time.UTC),
+			unit:  xtime.CalendarDay,
+			loc:   time.UTC,
+			want: [][2]time.Time{
+				{date(2023, 6, 1, 0, time.UTC), date(2023, 6, 1, 0, time.UTC)},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := newRange(c.start, c.end).Buckets(c.unit, c.loc)
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d buckets %v; want %d", len(got), got, len(c.want))
+			}
+			for i, want := range c.want {
+				if !got[i].Start().Equal(want[0]) || !got[i].End().Equal(want[1]) {
+					t.Errorf("bucket %d == {%v, %v}, want {%v, %v}", i, got[i].Start(), got[i].End(), want[0], want[1])
+				}
+				if got[i].Start().Location() != c.loc {
+					t.Errorf("bucket %d on location %v, want %v", i, got[i].Start().Location(), c.loc)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeJSON(t *testing.T) {
+	r := newRange(tm(1, 0), time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC))
+
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"start":"2023-01-01T01:00:00Z","end":"2023-01-02T03:04:05.000000006Z"}`
+	if string(encoded) != want {
+		t.Fatalf("got %s; want %s", encoded, want)
+	}
+
+	var got xtime.Range
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Start().Equal(r.Start()) || !got.End().Equal(r.End()) {
+		t.Fatalf("got {%v, %v}; want {%v, %v}", got.Start(), got.End(), r.Start(), r.End())
+	}
+
+	// Ranges are also encoded as text, like when used as map keys
+	encoded, err = json.Marshal(map[xtime.Range]int{r: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"2023-01-01T01:00:00Z/2023-01-02T03:04:05.000000006Z":1}`
+	if string(encoded) != want {
+		t.Fatalf("got %s; want %s", encoded, want)
+	}
+
+	var gotMap map[xtime.Range]int
+	if err := json.Unmarshal(encoded, &gotMap); err != nil {
+		t.Fatal(err)
+	}
+	if len(gotMap) != 1 {
+		t.Fatalf("got %v; want single range", gotMap)
+	}
+	for got := range gotMap {
+		if !got.Start().Equal(r.Start()) || !got.End().Equal(r.End()) {
+			t.Fatalf("got {%v, %v}; want {%v, %v}", got.Start(), got.End(), r.Start(), r.End())
+		}
+	}
+}
+
+func TestRangeUnmarshalInvalid(t *testing.T) {
+	cases := []string{
+		`{"start":"2023-01-02T00:00:00Z","end":"2023-01-01T00:00:00Z"}`,
+		`{"start":"0001-01-01T00:00:00Z","end":"2023-01-01T00:00:00Z"}`,
+		`{"end":"2023-01-01T00:00:00Z"}`,
+		`{"start":"2023-01-01","end":"2023-01-02"}`,
+		`"2023-01-01T00:00:00Z"`,
+		`{}`,
+	}
+	for _, c := range cases {
+		var r xtime.Range
+		if err := json.Unmarshal([]byte(c), &r); err == nil {
+			t.Errorf("json.Unmarshal(%s) == {%v, %v}; want error", c, r.Start(), r.End())
+		}
+	}
+
+	textCases := []string{
+		"",
+		"2023-01-01T00:00:00Z",
+		"2023-01-02T00:00:00Z/2023-01-01T00:00:00Z",
+		"2023-01-01T00:00:00Z/invalid",
+	}
+	for _, c := range textCases {
+		var r xtime.Range
+		if err := r.UnmarshalText([]byte(c)); err == nil {
+			t.Errorf("Range.UnmarshalText(%q) == {%v, %v}; want error", c, r.Start(), r.End())
+		}
+	}
+}
+
 func newRange(start, end time.Time) xtime.Range {
 	tr, err := xtime.NewRange(start, end)
 	if err != nil {